@@ -2,9 +2,11 @@ package main
 
 import (
 	"challenge16/internal/config"
+	"challenge16/internal/data"
 	"challenge16/internal/regions"
 	"challenge16/internal/server"
 	"fmt"
+	"time"
 )
 
 const (
@@ -14,14 +16,35 @@ const (
 
 func main() {
 	//initialize the region data
-	regions.LoadDataIntoMap(csvFile)
+	err := regions.LoadDataIntoMap(csvFile)
+	if err != nil {
+		panic("Couldn't load the region data. Error: " + err.Error())
+	}
 
 	//initialize the environment configuration
 	config.LoadEnv(envPath)
 
-	app := server.NewServer(config.RateLimit)
+	//open the distributor data store (restoring the data persisted by previous runs, for the file store)
+	store, err := data.OpenStore(config.Store, config.DataDir, config.SnapshotInterval)
+	if err != nil {
+		panic("Couldn't load the distributor data. Error: " + err.Error())
+	}
+	databank := data.NewDataBank(store)
+	defer databank.Close()
+
+	//expire time-bound contracts (and start the ones that become valid) in the background
+	stopExpiry := databank.StartExpiryScheduler(time.Duration(config.ExpiryInterval) * time.Second)
+	defer stopExpiry()
+
+	//reload the region data when its file changes, if asked to
+	if config.RegionsWatchInterval > 0 {
+		stopWatch := databank.StartRegionWatcher(time.Duration(config.RegionsWatchInterval) * time.Second)
+		defer stopWatch()
+	}
+
+	app := server.NewServer(config.RateLimit, databank)
 
-	err := app.Listen(fmt.Sprintf(":%s", config.Port))
+	err = app.Listen(fmt.Sprintf(":%s", config.Port))
 	if err != nil {
 		panic("Couldn't start the server. Error: " + err.Error())
 	}
@@ -10,12 +10,28 @@ import (
 )
 
 const (
-	defaultPort = "4010"
+	defaultPort                 = "4010"
+	defaultStore                = "file"
+	defaultDataDir              = "data"
+	defaultSnapshotInterval     = 1000
+	defaultExpiryInterval       = 60
+	defaultRegionsWatchInterval = 0
 )
 
 var (
 	Port      string
 	RateLimit int
+
+	// Store is the storage backend of the distributor data: "memory" or "file"
+	Store string
+	// DataDir is where the write-ahead log and snapshots of the distributor data are kept
+	DataDir string
+	// SnapshotInterval is the number of logged mutations after which the log is compacted into a snapshot
+	SnapshotInterval int
+	// ExpiryInterval is how often, in seconds, time-bound contracts are checked for starting or expiring. It must be positive
+	ExpiryInterval int
+	// RegionsWatchInterval is how often, in seconds, the region file is checked for changes to reload, 0 to never check
+	RegionsWatchInterval int
 )
 
 // func init() {
@@ -44,4 +60,44 @@ func LoadEnv(path string) {
 		}
 	}
 
+	Store = os.Getenv("STORE")
+	if Store == "" {
+		Store = defaultStore
+	}
+
+	DataDir = os.Getenv("DATA_DIR")
+	if DataDir == "" {
+		DataDir = defaultDataDir
+	}
+
+	SnapshotInterval, err = strconv.Atoi(os.Getenv("SNAPSHOT_INTERVAL"))
+	if err != nil {
+		if os.Getenv("SNAPSHOT_INTERVAL") == "" {
+			SnapshotInterval = defaultSnapshotInterval
+		} else {
+			log.Fatal("Error loading SNAPSHOT_INTERVAL from .env file. err", err)
+		}
+	}
+
+	ExpiryInterval, err = strconv.Atoi(os.Getenv("EXPIRY_INTERVAL"))
+	if err != nil {
+		if os.Getenv("EXPIRY_INTERVAL") == "" {
+			ExpiryInterval = defaultExpiryInterval
+		} else {
+			log.Fatal("Error loading EXPIRY_INTERVAL from .env file. err", err)
+		}
+	}
+	if ExpiryInterval <= 0 {
+		log.Fatal("Error loading EXPIRY_INTERVAL from .env file. err ", fmt.Errorf("it must be a positive number of seconds, got %d", ExpiryInterval))
+	}
+
+	RegionsWatchInterval, err = strconv.Atoi(os.Getenv("REGIONS_WATCH_INTERVAL"))
+	if err != nil {
+		if os.Getenv("REGIONS_WATCH_INTERVAL") == "" {
+			RegionsWatchInterval = defaultRegionsWatchInterval
+		} else {
+			log.Fatal("Error loading REGIONS_WATCH_INTERVAL from .env file. err", err)
+		}
+	}
+
 }
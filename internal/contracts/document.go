@@ -0,0 +1,69 @@
+package contracts
+
+import (
+	"challenge16/internal/dto"
+	"strings"
+)
+
+// Block is a contract of a document, along with its own text and the line of its heading.
+type Block struct {
+	Contract *dto.Contract
+	Line     int
+	Text     string
+
+	heading token
+}
+
+// ParseDocument parses a document of one or more contracts, each starting at its "Permissions for" heading.
+// On failure, the error is an ErrorList of every mistake found, in any of the contracts.
+func ParseDocument(text string) ([]Block, error) {
+	blocks, errs := parseDocument(text)
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+	return blocks, nil
+}
+
+// parseDocument splits the text into contracts at their headings and parses each of them, returning the contracts even if they have mistakes.
+// Lines before the first heading are parsed as a contract of their own, missing its heading.
+func parseDocument(text string) ([]Block, ErrorList) {
+	var errs ErrorList
+	documentLines := lines(lex(text))
+	if len(documentLines) == 0 {
+		errs.add(token{line: 1, column: 1}, false, `expected the heading "Permissions for <distributor>"`)
+		return nil, errs
+	}
+
+	starts := []int{}
+	for i, line := range documentLines {
+		if i == 0 || isHeading(line) {
+			starts = append(starts, i)
+		}
+	}
+
+	textLines := strings.Split(text, "\n")
+	blocks := make([]Block, len(starts))
+	for i, start := range starts {
+		end := len(documentLines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		heading := documentLines[start][0]
+
+		//the text runs up to the next heading, comments and blank lines included
+		lastLine := len(textLines)
+		if end < len(documentLines) {
+			lastLine = documentLines[end][0].line - 1
+		}
+		blockText := strings.Join(textLines[heading.line-1:lastLine], "\n")
+
+		blocks[i] = Block{
+			Contract: parseContract(documentLines[start:end], &errs),
+			Line:     heading.line,
+			Text:     strings.TrimRight(blockText, " \t\r\n"),
+			heading:  heading,
+		}
+	}
+	return blocks, errs
+}
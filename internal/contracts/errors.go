@@ -0,0 +1,57 @@
+package contracts
+
+import (
+	"challenge16/internal/regions"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Error is a mistake in a contract, at the 1-based line and column it was found.
+type Error struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+
+	Candidates []regions.Candidate `json:"candidates,omitempty"` //the regions an ambiguous name may refer to
+
+	unknownRegion bool //the mistake is a region that isn't in the catalog
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ErrorList is every mistake found in a contract, sorted by position.
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	messages := make([]string, len(list))
+	for i, err := range list {
+		messages[i] = err.Error()
+	}
+	return "Invalid contract, " + strings.Join(messages, "; ")
+}
+
+// UnknownRegionsOnly tells whether the contract is well-formed but for regions that aren't in the catalog.
+func (list ErrorList) UnknownRegionsOnly() bool {
+	for _, err := range list {
+		if !err.unknownRegion {
+			return false
+		}
+	}
+	return len(list) > 0
+}
+
+func (list *ErrorList) add(tok token, unknownRegion bool, format string, args ...interface{}) {
+	*list = append(*list, &Error{Line: tok.line, Column: tok.column, Message: fmt.Sprintf(format, args...), unknownRegion: unknownRegion})
+}
+
+func (list ErrorList) sort() {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Line != list[j].Line {
+			return list[i].Line < list[j].Line
+		}
+		return list[i].Column < list[j].Column
+	})
+}
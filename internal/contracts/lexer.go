@@ -0,0 +1,89 @@
+// Package contracts parses the contract language:
+//
+//	# comments run to the end of the line
+//	Permissions for DISTRIBUTOR2 < DISTRIBUTOR1 ON FILM:F001
+//	INCLUDE: IN
+//	EXCLUDE: KA-IN
+//	VALID FROM: 2025-01-01
+//	VALID UNTIL: 2025-06-30T18:30:00Z
+//	EXCLUSIVE: YES
+//
+// The heading comes first, followed by one clause per line, in any order. Blank lines are ignored, and lines may end with \r\n.
+// A document holds one or more contracts, each running from its heading to the next one.
+package contracts
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenWord    tokenKind = iota //a run of characters other than spaces, ':', '<' and '#'
+	tokenColon                    //:
+	tokenLess                     //<
+	tokenNewline                  //end of a line
+)
+
+// token is a lexeme of the contract, with the 1-based line and column (in characters) it starts at.
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+// lex splits the text into tokens. Comments are dropped, and every line (the last one included) ends with a tokenNewline.
+func lex(text string) []token {
+	tokens := []token{}
+	for i, line := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSuffix(line, "\r"))
+		lineNumber := i + 1
+
+		for column := 0; column < len(runes); {
+			switch r := runes[column]; {
+			case r == '#':
+				column = len(runes)
+			case unicode.IsSpace(r):
+				column++
+			case r == ':':
+				tokens = append(tokens, token{kind: tokenColon, text: ":", line: lineNumber, column: column + 1})
+				column++
+			case r == '<':
+				tokens = append(tokens, token{kind: tokenLess, text: "<", line: lineNumber, column: column + 1})
+				column++
+			default:
+				start := column
+				for column < len(runes) && !isDelimiter(runes[column]) {
+					column++
+				}
+				tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:column]), line: lineNumber, column: start + 1})
+			}
+		}
+		tokens = append(tokens, token{kind: tokenNewline, line: lineNumber, column: len(runes) + 1})
+	}
+	return tokens
+}
+
+func isDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == ':' || r == '<' || r == '#'
+}
+
+// lines groups the tokens by line, dropping the newlines and the blank lines.
+func lines(tokens []token) [][]token {
+	grouped := [][]token{}
+	line := []token{}
+	for _, tok := range tokens {
+		if tok.kind != tokenNewline {
+			line = append(line, tok)
+			continue
+		}
+		if len(line) > 0 {
+			//the newline is kept as the end of the line, for errors about what's missing at the end
+			grouped = append(grouped, append(line, tok))
+		}
+		line = []token{}
+	}
+	return grouped
+}
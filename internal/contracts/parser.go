@@ -0,0 +1,264 @@
+package contracts
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"errors"
+	"strings"
+	"time"
+)
+
+// parser builds a contract from the lines of its text, recording every mistake and carrying on with the next line.
+type parser struct {
+	contract dto.Contract
+	errs     ErrorList
+
+	validFromLine, validUntilLine, exclusiveLine int //the line each clause was first given on, 0 if not yet
+	included                                     bool
+
+	regionLines []regionLine //the INCLUDE and EXCLUDE lines added to the contract, in order, for checkRegions
+	validUntil  token        //the VALID UNTIL keyword, for checkValidity
+}
+
+// Parse parses the text of a contract. On failure, the error is an ErrorList of every mistake found.
+func Parse(text string) (*dto.Contract, error) {
+	blocks, errs := parseDocument(text)
+	if len(blocks) > 1 {
+		errs.add(blocks[1].heading, false, "expected a single contract, found a second heading")
+	}
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+	return blocks[0].Contract, nil
+}
+
+// parseContract parses the lines of a contract, its heading first, adding its mistakes to errs.
+func parseContract(contractLines [][]token, errs *ErrorList) *dto.Contract {
+	p := &parser{contract: dto.Contract{
+		Permissions: dto.Permissions{
+			IncludedCountries: make(map[string]bool),
+			IncludedProvinces: make(map[string]map[string]bool),
+			IncludedCities:    make(map[string]map[string]map[string]bool),
+			ExcludedProvinces: make(map[string]map[string]bool),
+			ExcludedCities:    make(map[string]map[string]map[string]bool),
+		},
+	}}
+
+	heading := contractLines[0]
+	p.parseHeading(heading)
+	for _, line := range contractLines[1:] {
+		p.parseClause(line)
+	}
+	if !p.included {
+		p.errs.add(heading[0], false, "no region is included, the contract needs at least one INCLUDE line")
+	} else {
+		p.checkRegions() //without inclusions, every exclusion would be reported too
+	}
+	p.checkValidity()
+
+	*errs = append(*errs, p.errs...)
+	return &p.contract
+}
+
+// isHeading tells whether the line starts a contract.
+func isHeading(line []token) bool {
+	return len(line) > 1 && line[0].text == "Permissions" && line[1].text == "for"
+}
+
+// parseHeading parses "Permissions for <recipient> [< <parent> [< ...]] [ON FILM: <film>]".
+func (p *parser) parseHeading(line []token) {
+	if len(line) < 3 || line[0].text != "Permissions" || line[1].text != "for" {
+		p.errs.add(line[0], false, `expected the heading "Permissions for <distributor>", found %q`, lineText(line))
+		return
+	}
+	rest := line[2:]
+
+	for i := 0; i+1 < len(rest); i++ {
+		if rest[i].text == "ON" && rest[i+1].text == "FILM" {
+			if film, ok := p.value(rest[i+2:], "ON FILM", "a film id"); ok {
+				p.contract.Film = film
+			}
+			rest = append(rest[:i:i], rest[len(rest)-1]) //the end of the line
+			break
+		}
+	}
+
+	//distributors are separated by '<'. The words of a name are joined, for tolerance to stray spaces
+	lineage := []string{}
+	seen := make(map[string]int)
+	name, start := "", rest[0]
+	for _, tok := range rest {
+		switch tok.kind {
+		case tokenWord:
+			name += tok.text
+			continue
+		case tokenColon:
+			p.errs.add(tok, false, "unexpected ':' in the heading")
+			continue
+		}
+		//'<' or the end of the line
+		switch {
+		case name == "":
+			p.errs.add(tok, false, "expected a distributor name")
+		case seen[name] > 0:
+			p.errs.add(start, false, "duplicate distributor %s in the heading", name)
+		default:
+			seen[name] = len(lineage) + 1
+			lineage = append(lineage, name)
+		}
+		name = ""
+		start = tok
+		if start.kind == tokenLess {
+			start = token{line: tok.line, column: tok.column + 1}
+		}
+	}
+
+	if len(lineage) == 0 {
+		return
+	}
+	p.contract.Lineage = lineage
+	p.contract.ContractRecipient = lineage[0]
+	if len(lineage) > 1 {
+		p.contract.ParentDistributor = &lineage[1]
+	}
+}
+
+// parseClause parses a line following the heading.
+func (p *parser) parseClause(line []token) {
+	keyword := line[0]
+	switch {
+	case keyword.text == "INCLUDE", keyword.text == "EXCLUDE":
+		if keyword.text == "INCLUDE" {
+			p.included = true //even if its region is wrong, which is reported instead
+		}
+		region, ok := p.value(line[1:], keyword.text, "a region")
+		if !ok {
+			return
+		}
+		regionToken := line[2]
+		region, err := regions.Resolve(region)
+		var ambiguous *regions.AmbiguousRegionError
+		if errors.As(err, &ambiguous) {
+			p.errs.add(regionToken, false, "%s", err.Error())
+			p.errs[len(p.errs)-1].Candidates = ambiguous.Candidates
+			return
+		}
+		if keyword.text == "INCLUDE" {
+			if err := p.contract.AddIncludedRegion(region); err != nil {
+				p.errs.add(regionToken, strings.HasPrefix(err.Error(), regions.InvalidRegionPrefix), "%s", err.Error())
+				return
+			}
+			p.addRegionLine(regionToken, region, true)
+			return
+		}
+		if !strings.Contains(region, "-") && regions.CheckCountry(region) {
+			p.errs.add(regionToken, false, "excluding a country (%s) is meaningless since there's no world-level inclusion to exclude from", region)
+			return
+		}
+		if err := p.contract.AddExcludedRegion(region); err != nil {
+			p.errs.add(regionToken, strings.HasPrefix(err.Error(), regions.InvalidRegionPrefix), "%s", err.Error())
+			return
+		}
+		p.addRegionLine(regionToken, region, false)
+
+	case keyword.text == "VALID":
+		if line[1].text != "FROM" && line[1].text != "UNTIL" {
+			p.errs.add(line[1], false, "expected FROM or UNTIL after VALID")
+			return
+		}
+		clause := "VALID " + line[1].text
+		bound, firstLine := &p.contract.ValidFrom, &p.validFromLine
+		if line[1].text == "UNTIL" {
+			bound, firstLine = &p.contract.ValidUntil, &p.validUntilLine
+		}
+		if *firstLine > 0 {
+			p.errs.add(keyword, false, "duplicate %s line, first given on line %d", clause, *firstLine)
+			return
+		}
+		*firstLine = keyword.line
+		if line[1].text == "UNTIL" {
+			p.validUntil = keyword
+		}
+
+		value, ok := p.value(line[2:], clause, "a date (2006-01-02) or an RFC 3339 time (2006-01-02T15:04:05Z07:00)")
+		if !ok {
+			return
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			p.errs.add(line[3], false, "invalid time %q after %s:, expected a date (2006-01-02) or an RFC 3339 time (2006-01-02T15:04:05Z07:00)", value, clause)
+			return
+		}
+		*bound = t
+
+	case keyword.text == "EXCLUSIVE":
+		if p.exclusiveLine > 0 {
+			p.errs.add(keyword, false, "duplicate EXCLUSIVE line, first given on line %d", p.exclusiveLine)
+			return
+		}
+		p.exclusiveLine = keyword.line
+
+		value, ok := p.value(line[1:], "EXCLUSIVE", "YES or NO")
+		if !ok {
+			return
+		}
+		switch strings.ToUpper(value) {
+		case "YES":
+			p.contract.Exclusive = true
+		case "NO":
+		default:
+			p.errs.add(line[2], false, "expected YES or NO after EXCLUSIVE:, found %q", value)
+		}
+
+	default:
+		p.errs.add(keyword, false, "unexpected %q, expected INCLUDE, EXCLUDE, VALID FROM, VALID UNTIL or EXCLUSIVE", lineText(line))
+	}
+}
+
+// value returns the value following "<clause>:" on a line, made of the words of rest after the colon.
+// The words are joined, for tolerance to stray spaces; the colons too, as they are part of times.
+func (p *parser) value(rest []token, clause, expected string) (string, bool) {
+	if rest[0].kind != tokenColon {
+		p.errs.add(rest[0], false, "expected ':' after %s", clause)
+		return "", false
+	}
+	value := ""
+	for _, tok := range rest[1:] {
+		switch tok.kind {
+		case tokenWord, tokenColon:
+			value += tok.text
+		case tokenLess:
+			p.errs.add(tok, false, "unexpected '<' after %s:", clause)
+			return "", false
+		}
+	}
+	if value == "" {
+		p.errs.add(rest[0], false, "expected %s after %s:", expected, clause)
+		return "", false
+	}
+	return value, true
+}
+
+// lineText returns the text of the tokens, for error messages.
+func lineText(line []token) string {
+	words := make([]string, 0, len(line))
+	for _, tok := range line {
+		if tok.kind != tokenNewline {
+			words = append(words, tok.text)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// parseTime parses the time of a VALID FROM/UNTIL line: either a date (midnight UTC) or an RFC 3339 time.
+func parseTime(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,95 @@
+package contracts
+
+import (
+	"challenge16/internal/regions"
+	"time"
+)
+
+// regionLine is an INCLUDE or EXCLUDE line added to the contract.
+type regionLine struct {
+	tok      token //the region on the line
+	region   regions.Region
+	included bool
+}
+
+func (p *parser) addRegionLine(tok token, code string, included bool) {
+	region, err := regions.GetRegionDetails(code)
+	if err != nil {
+		return
+	}
+	p.regionLines = append(p.regionLines, regionLine{tok: tok, region: region, included: included})
+}
+
+// checkRegions reports the INCLUDE and EXCLUDE lines that contradict each other, each at the line at fault:
+// an included region has only exclusions of its sub-regions, a region is either included or excluded,
+// and a region is excluded only from an included parent.
+func (p *parser) checkRegions() {
+	included := make(map[string]regionLine) //the first line including each region
+	for _, line := range p.regionLines {
+		if _, exists := included[regionKey(line.region)]; line.included && !exists {
+			included[regionKey(line.region)] = line
+		}
+	}
+
+	for _, line := range p.regionLines {
+		r := line.region
+		country, hasCountry := included[r.CountryCode]
+		province, hasProvince := included[r.ProvinceCode+"-"+r.CountryCode]
+
+		switch {
+		case line.included && r.Type == regions.PROVINCE:
+			if hasCountry {
+				p.errs.add(line.tok, false, "province %s in country %s is included, but the country is also included on line %d. There should only be exclusions of sub-regions for an included region", r.ProvinceCode, r.CountryCode, country.tok.line)
+			}
+
+		case line.included && r.Type == regions.CITY:
+			if hasCountry {
+				p.errs.add(line.tok, false, "city %s in province %s in country %s is included, but the country is also included on line %d. There should only be exclusions of sub-regions for an included region", r.CityCode, r.ProvinceCode, r.CountryCode, country.tok.line)
+			} else if hasProvince {
+				p.errs.add(line.tok, false, "city %s in province %s in country %s is included, but the province is also included on line %d. There should only be exclusions of sub-regions for an included region", r.CityCode, r.ProvinceCode, r.CountryCode, province.tok.line)
+			}
+
+		case r.Type == regions.PROVINCE:
+			if hasProvince {
+				p.errs.add(line.tok, false, "province %s in country %s is excluded, but it's also included on line %d. It should be either included or excluded", r.ProvinceCode, r.CountryCode, province.tok.line)
+				continue
+			}
+			if !hasCountry {
+				p.errs.add(line.tok, false, "province %s in country %s is excluded, but the country is not included. A region can be excluded only if its parent is included", r.ProvinceCode, r.CountryCode)
+			}
+			for _, city := range p.regionLines {
+				if city.included && city.region.Type == regions.CITY && city.region.CountryCode == r.CountryCode && city.region.ProvinceCode == r.ProvinceCode {
+					p.errs.add(line.tok, false, "province %s in country %s is excluded, but its city %s is included on line %d. A region cannot be excluded while including its sub-regions", r.ProvinceCode, r.CountryCode, city.region.CityCode, city.tok.line)
+				}
+			}
+
+		case r.Type == regions.CITY:
+			if city, exists := included[regionKey(r)]; exists {
+				p.errs.add(line.tok, false, "city %s in province %s in country %s is excluded, but it's also included on line %d. It should be either included or excluded", r.CityCode, r.ProvinceCode, r.CountryCode, city.tok.line)
+				continue
+			}
+			if !hasCountry && !hasProvince {
+				p.errs.add(line.tok, false, "city %s in province %s in country %s is excluded, but the country is not included and the province is not included. A region cannot be excluded while its parent region is not included", r.CityCode, r.ProvinceCode, r.CountryCode)
+			}
+		}
+	}
+}
+
+// checkValidity reports a contract that doesn't start before it ends, at its VALID UNTIL line.
+func (p *parser) checkValidity() {
+	from, until := p.contract.ValidFrom, p.contract.ValidUntil
+	if from != nil && until != nil && !from.Before(*until) {
+		p.errs.add(p.validUntil, false, "the contract is valid from %s (line %d) until %s, it must start before it ends", from.Format(time.RFC3339), p.validFromLine, until.Format(time.RFC3339))
+	}
+}
+
+// regionKey returns the code of the region: COUNTRY, PROVINCE-COUNTRY or CITY-PROVINCE-COUNTRY.
+func regionKey(r regions.Region) string {
+	switch r.Type {
+	case regions.PROVINCE:
+		return r.ProvinceCode + "-" + r.CountryCode
+	case regions.CITY:
+		return r.CityCode + "-" + r.ProvinceCode + "-" + r.CountryCode
+	}
+	return r.CountryCode
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	"challenge16/internal/response"
+	"time"
+)
+
+// cascadeToDescendants re-filters the permissions of every descendant of the distributor, so that no sub-distributor
+// holds a region its parent doesn't hold ("child ⊆ parent").
+// It must run in the same transaction as the change made to the distributor, and returns the descendants whose permissions changed.
+func cascadeToDescendants(tx Tx, distributor string, now time.Time) []string {
+	affected := []string{}
+
+	for _, child := range getChildren(tx, distributor) {
+		record, _ := tx.Get(child)
+		if refreshed, changed := materialize(tx, record, now); changed || refreshed.timed() != record.timed() {
+			tx.Put(child, refreshed)
+			if changed {
+				affected = append(affected, child)
+			}
+		}
+
+		//descending even if the child is unchanged, in case the hierarchy was inconsistent before
+		affected = append(affected, cascadeToDescendants(tx, child, now)...)
+	}
+	return affected
+}
+
+// cascadeResponse is the success response of a change that was cascaded to the given descendants.
+func cascadeResponse(affected []string) response.Response {
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"affected_descendants": affected,
+	})
+}
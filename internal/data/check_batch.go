@@ -0,0 +1,53 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"errors"
+	"fmt"
+)
+
+// CheckBatch checks every distributor/region pair against the same state of the DataBank.
+// Regions may be written with names, as in contracts. A pair that can't be checked, like one whose region name is ambiguous,
+// gets its own error, without failing the rest of the batch.
+func (db *DataBank) CheckBatch(checks []dto.PermissionCheck) response.Response {
+	results := make([]dto.PermissionCheckResult, len(checks))
+
+	type parsedRegion struct {
+		region regions.Region
+		err    error
+	}
+	parsed := make(map[string]parsedRegion)
+
+	db.store.View(func(view ReadTx) {
+		for i, check := range checks {
+			result := dto.PermissionCheckResult{Distributor: check.Distributor, Region: check.Region}
+
+			p, ok := parsed[check.Region]
+			if !ok {
+				var code string
+				if code, p.err = regions.Resolve(check.Region); p.err == nil {
+					p.region, p.err = regions.GetRegionDetails(code)
+				}
+				parsed[check.Region] = p
+			}
+
+			var ambiguous *regions.AmbiguousRegionError
+			if errors.As(p.err, &ambiguous) {
+				result.ErrorCode, result.Error, result.Candidates = AMBIGUOUS_REGION, p.err.Error(), ambiguous.Candidates
+			} else if p.err != nil {
+				result.ErrorCode, result.Error = REGION_NOT_FOUND, p.err.Error()
+			} else if _, status := isAllowed(view, check.Distributor, p.region); status == "" {
+				result.ErrorCode, result.Error = DISTRIBUTOR_NOT_FOUND, fmt.Sprintf("distributor %s not found", check.Distributor)
+			} else {
+				result.Status = status
+			}
+			results[i] = result
+		}
+	})
+
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"results": results,
+	})
+}
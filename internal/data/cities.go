@@ -0,0 +1,97 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"fmt"
+	"sort"
+)
+
+// coveredCities expands p into the cities they cover, sorted by country, province and city code.
+// country and province (both optional) restrict the expansion to a country or a province of it.
+func coveredCities(p permissions.PermissionSet, country, province string) []dto.CoveredCity {
+	marked := make(map[string]map[string]map[string]bool)
+	p.MarkedRegions(marked)
+
+	//only the countries the permissions mark can have cities covered
+	countryCodes := make([]string, 0, len(marked))
+	for countryCode := range marked {
+		if country == "" || countryCode == country {
+			countryCodes = append(countryCodes, countryCode)
+		}
+	}
+	sort.Strings(countryCodes)
+
+	catalog := regions.Countries()
+	cities := []dto.CoveredCity{}
+	for _, countryCode := range countryCodes {
+		provinces := catalog[countryCode].Provinces
+
+		provinceCodes := make([]string, 0, len(provinces))
+		for provinceCode := range provinces {
+			if province == "" || provinceCode == province {
+				provinceCodes = append(provinceCodes, provinceCode)
+			}
+		}
+		sort.Strings(provinceCodes)
+
+		for _, provinceCode := range provinceCodes {
+			cityCodes := make([]string, 0, len(provinces[provinceCode].Cities))
+			for cityCode := range provinces[provinceCode].Cities {
+				if p.Covers(countryCode, provinceCode, cityCode) {
+					cityCodes = append(cityCodes, cityCode)
+				}
+			}
+			sort.Strings(cityCodes)
+
+			for _, cityCode := range cityCodes {
+				cities = append(cities, dto.CoveredCity{
+					Code:     cityCode + "-" + provinceCode + "-" + countryCode,
+					Name:     provinces[provinceCode].Cities[cityCode],
+					Province: provinceCode + "-" + countryCode,
+					Country:  countryCode,
+				})
+			}
+		}
+	}
+	return cities
+}
+
+// GetDistributorCities returns one page of the cities the distributor covers, along with the number of cities covered per province.
+func (db *DataBank) GetDistributorCities(distributor, country, province string, page, pageSize int) response.Response {
+	if province != "" && !regions.CheckProvince(country, province) {
+		return response.CreateError(404, REGION_NOT_FOUND, fmt.Errorf("province %s-%s not found", province, country))
+	}
+	if country != "" && !regions.CheckCountry(country) {
+		return response.CreateError(404, REGION_NOT_FOUND, fmt.Errorf("country %s not found", country))
+	}
+
+	held, ok := db.getDistributorPermissionCopy(distributor)
+	if !ok {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+
+	cities := coveredCities(held, country, province)
+	provinceCounts := make(map[string]int)
+	for _, city := range cities {
+		provinceCounts[city.Province]++
+	}
+
+	//pages past the end are empty; they're compared before multiplying, as a huge page would overflow
+	start := len(cities)
+	if page-1 <= len(cities)/pageSize {
+		start = min((page-1)*pageSize, len(cities))
+	}
+	end := min(start+pageSize, len(cities))
+
+	return response.CreateSuccess(200, "SUCCESS", dto.GetCitiesData{
+		Distributor:    distributor,
+		Page:           page,
+		PageSize:       pageSize,
+		Total:          len(cities),
+		Cities:         cities[start:end],
+		ProvinceCounts: provinceCounts,
+	})
+}
@@ -0,0 +1,40 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/response"
+	"fmt"
+)
+
+// ComparePermissions splits the regions held by a and b (on the film, if given) into the ones only a holds, only b holds, and both hold,
+// each in its most compact form.
+func (db *DataBank) ComparePermissions(a, b, film string) response.Response {
+	var resp response.Response
+	db.store.View(func(view ReadTx) {
+		scoped, ok := scopeView(view, film)
+		if !ok {
+			resp = filmNotFoundResponse(film)
+			return
+		}
+
+		held := make([]permissions.PermissionSet, 2)
+		for i, distributor := range []string{a, b} {
+			record, exists := scoped.Get(distributor)
+			if !exists {
+				resp = response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+				return
+			}
+			held[i] = permissions.FromDTO(record.Permissions)
+		}
+
+		resp = response.CreateSuccess(200, "SUCCESS", dto.PermissionComparison{
+			A:     a,
+			B:     b,
+			OnlyA: toRegionLists(held[0].Subtract(held[1]).Normalize()),
+			OnlyB: toRegionLists(held[1].Subtract(held[0]).Normalize()),
+			Both:  toRegionLists(held[0].Intersect(held[1]).Normalize()),
+		})
+	})
+	return resp
+}
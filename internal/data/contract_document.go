@@ -0,0 +1,149 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/response"
+	"fmt"
+	"strings"
+)
+
+const (
+	ContractApplied    = "applied"
+	ContractFailed     = "failed"
+	ContractRolledBack = "rolled_back"
+	ContractSkipped    = "skipped"
+)
+
+// DocumentContract is a contract of a document, along with its own text and the line of its heading.
+type DocumentContract struct {
+	Contract dto.Contract
+	Text     string
+	Line     int
+}
+
+// recipientKey identifies the recipient of a contract within its scope: the whole catalog, or a film.
+func recipientKey(film, distributor string) string {
+	return film + "/" + distributor
+}
+
+// contractOrder returns the order to apply the contracts of a document in: each one after the contracts of the document
+// on the distributors above its recipient (the rest of its heading's chain, in the same scope), otherwise in the order of the document.
+func contractOrder(document []DocumentContract) ([]int, error) {
+	blocksOf := make(map[string][]int)
+	for i, block := range document {
+		key := recipientKey(block.Contract.Film, block.Contract.ContractRecipient)
+		blocksOf[key] = append(blocksOf[key], i)
+	}
+
+	dependencies := make([][]int, len(document))
+	for i, block := range document {
+		lineage := block.Contract.Lineage
+		if len(lineage) == 0 && block.Contract.ParentDistributor != nil {
+			lineage = []string{block.Contract.ContractRecipient, *block.Contract.ParentDistributor}
+		}
+		for _, ancestor := range lineage[min(1, len(lineage)):] {
+			dependencies[i] = append(dependencies[i], blocksOf[recipientKey(block.Contract.Film, ancestor)]...)
+		}
+	}
+
+	order := make([]int, 0, len(document))
+	applied := make([]bool, len(document))
+	for len(order) < len(document) {
+		next := -1
+		for i := range document {
+			if applied[i] {
+				continue
+			}
+			ready := true
+			for _, dependency := range dependencies[i] {
+				ready = ready && applied[dependency]
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			pending := []string{}
+			for i, block := range document {
+				if !applied[i] {
+					pending = append(pending, fmt.Sprintf("%s (line %d)", block.Contract.ContractRecipient, block.Line))
+				}
+			}
+			return nil, fmt.Errorf("the contracts of %s are each under another one, their hierarchy has a cycle", strings.Join(pending, ", "))
+		}
+		applied[next] = true
+		order = append(order, next)
+	}
+	return order, nil
+}
+
+// ApplyContracts applies the contracts of a document in one transaction, parents first: either all of them are applied, or none.
+// Each contract is applied as ApplyContract does, recorded as a version with its own text, and reported on in data.results.
+func (db *DataBank) ApplyContracts(document []DocumentContract, options ContractOptions) response.Response {
+	order, err := contractOrder(document)
+	if err != nil {
+		return response.CreateError(400, "INVALID_CONTRACT", fmt.Errorf("invalid contracts, err: %v", err))
+	}
+
+	results := make([]dto.ContractResult, len(order))
+	for n, i := range order {
+		contract := document[i].Contract
+		results[n] = dto.ContractResult{
+			Block:       i + 1,
+			Line:        document[i].Line,
+			Distributor: contract.ContractRecipient,
+			Film:        contract.Film,
+			Status:      ContractSkipped,
+		}
+	}
+
+	failed := -1
+	err = db.update(func(tx Tx) error {
+		for n, i := range order {
+			contract := document[i].Contract
+			scoped, err := scopeTx(tx, contract.Film)
+			if err != nil {
+				failed = n
+				return err
+			}
+
+			options.Text = document[i].Text
+			outcome, err := applyContract(scoped, contract, options)
+			if err != nil {
+				failed = n
+				return err
+			}
+			results[n].Status = ContractApplied
+			results[n].Version = outcome.version
+			results[n].AffectedDescendants = outcome.affected
+			results[n].Warnings = outcome.warnings
+			results[n].Conflicts = outcome.conflicts
+		}
+		return nil
+	})
+	if err == nil {
+		return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+			"results": results,
+		})
+	}
+
+	resp := transactionErrorResponse(err)
+	for n := range results {
+		if results[n].Status == ContractApplied {
+			results[n].Status = ContractRolledBack
+			results[n].Version = 0 //never recorded
+		}
+	}
+	if failed >= 0 {
+		results[failed].Status = ContractFailed
+		results[failed].ResponseCode = resp.ResponseCode
+		results[failed].Error = resp.Error.Error()
+		results[failed].Details = resp.Data
+		resp.Error = fmt.Errorf("contract for %s on line %d: %w", results[failed].Distributor, results[failed].Line, resp.Error)
+	}
+	resp.Data = map[string]interface{}{
+		"results": results,
+	}
+	return resp
+}
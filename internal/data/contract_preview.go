@@ -0,0 +1,78 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"errors"
+)
+
+// errDryRun rolls back the transaction of a contract preview
+var errDryRun = errors.New("dry run")
+
+func toRegionLists(p permissions.PermissionSet) dto.RegionLists {
+	included, excluded := p.RegionLists()
+	return dto.RegionLists{Included: included, Excluded: excluded}
+}
+
+// PreviewContract applies the contract the way ApplyContract does, but rolls it back,
+// returning the resulting permissions of the recipient, how they differ from the current ones, and the lines trimmed by the parent filter.
+func (db *DataBank) PreviewContract(contract dto.Contract, options ContractOptions) response.Response {
+	recipient := contract.ContractRecipient
+	requested := permissions.FromDTO(contract.Permissions)
+
+	preview := dto.ContractPreview{DryRun: true, Distributor: recipient}
+	err := db.store.Update(func(tx Tx) error {
+		tx, err := scopeTx(&normalizingTx{Tx: tx}, contract.Film)
+		if err != nil {
+			return err
+		}
+
+		current, exists := getPermissionCopy(tx, recipient)
+		if !exists {
+			current = permissions.New()
+		}
+
+		outcome, err := applyContract(tx, contract, options)
+		if err != nil {
+			return err
+		}
+
+		result, _ := getPermissionCopy(tx, recipient)
+		preview.Permissions = toRegionLists(result)
+		preview.Gained = toRegionLists(result.Subtract(current))
+		preview.Lost = toRegionLists(current.Subtract(result))
+		preview.Trimmed = trimmedLines(requested, outcome.granted)
+		preview.Warnings = outcome.warnings
+		preview.Conflicts = outcome.conflicts
+		preview.AffectedDescendants = outcome.affected
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return transactionErrorResponse(err)
+	}
+	return response.CreateSuccess(200, "SUCCESS", preview)
+}
+
+// trimmedLines returns the INCLUDE lines of the requested permissions that cover regions the filtered permissions don't.
+func trimmedLines(requested, filtered permissions.PermissionSet) []dto.TrimmedLine {
+	dropped := requested.Subtract(filtered)
+
+	lines, _ := requested.RegionLists()
+
+	trimmed := []dto.TrimmedLine{}
+	for _, line := range lines {
+		region, err := regions.GetRegionDetails(line)
+		if err != nil {
+			continue
+		}
+		switch dropped.Decide(region) {
+		case FULLY_ALLOWED:
+			trimmed = append(trimmed, dto.TrimmedLine{Line: "INCLUDE: " + line, Trimmed: "fully"})
+		case PARTIALLY_ALLOWED:
+			trimmed = append(trimmed, dto.TrimmedLine{Line: "INCLUDE: " + line, Trimmed: "partially"})
+		}
+	}
+	return trimmed
+}
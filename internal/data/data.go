@@ -2,12 +2,15 @@ package data
 
 import (
 	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
 	"challenge16/internal/regions"
 	"challenge16/internal/response"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
-	"sync"
+	"time"
 )
 
 const (
@@ -19,8 +22,13 @@ const (
 	PROVINCE = "province"
 	CITY     = "city"
 
+	RemovalRestrict     = "restrict"
+	RemovalCascade      = "cascade"
+	RemovalOrphanRevoke = "orphan-revoke"
+
 	DISTRIBUTOR_NOT_FOUND = "DISTRIBUTOR_NOT_FOUND"
 	REGION_NOT_FOUND      = "REGION_NOT_FOUND"
+	AMBIGUOUS_REGION      = "AMBIGUOUS_REGION"
 	INTERNAL_SERVER_ERROR = "INTERNAL_SERVER_ERROR"
 )
 
@@ -34,528 +42,428 @@ var (
 
 type (
 	DataBank struct {
-		Distributors map[string]permissionData
-		mu           sync.RWMutex
+		store Store
+		index *regionIndex
+	}
+
+	// abortError rolls back a transaction, carrying the response to be returned instead
+	abortError struct {
+		response.Response
 	}
 )
 
-func NewDataBank() DataBank {
-	return DataBank{
-		Distributors: make(map[string]permissionData),
-		mu:           sync.RWMutex{},
+func NewDataBank(store Store) *DataBank {
+	return &DataBank{
+		store: store,
+		index: newRegionIndex(store),
 	}
 }
 
-func newPermissionData() permissionData {
-	return permissionData{
-		includedCountries: make(map[string]bool),
-		includedProvinces: make(map[string]map[string]bool),
-		excludedProvinces: make(map[string]map[string]bool),
-		includedCities:    make(map[string]map[string]map[string]bool),
-		excludedCities:    make(map[string]map[string]map[string]bool),
-	}
+// Close releases the resources held by the underlying store.
+func (db *DataBank) Close() error {
+	return db.store.Close()
 }
 
-func (db *DataBank) MarkInclusion(distributor, regionString string) response.Response {
-	if !db.distributorExists(distributor) {
-		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+func (e abortError) Error() string {
+	return e.Response.Error.Error()
+}
+
+// abort is returned from inside a transaction to roll it back and respond with resp.
+func abort(resp response.Response) error {
+	return abortError{resp}
+}
+
+// transactionErrorResponse converts the error returned by Store.Update into a response.
+func transactionErrorResponse(err error) response.Response {
+	var abortErr abortError
+	if errors.As(err, &abortErr) {
+		return abortErr.Response
 	}
+	return response.CreateError(500, INTERNAL_SERVER_ERROR, fmt.Errorf("error persisting change: %w", err))
+}
 
-	region, err := regions.GetRegionDetails(regionString)
+// update runs fn in a store transaction, and keeps the region index and the permission history up to date with what it wrote,
+// which it stores normalized. Every write to the store must go through it.
+func (db *DataBank) update(fn func(tx Tx) error) error {
+	//the index is locked before the commit and until it reflects it, so that no lookup sees the store ahead of the index
+	db.index.mu.Lock()
+	defer db.index.mu.Unlock()
+
+	written := make(map[string]bool)
+	err := db.store.Update(func(tx Tx) error {
+		return fn(&normalizingTx{Tx: &indexingTx{Tx: &historyTx{Tx: tx, now: time.Now().UTC()}, written: written}})
+	})
 	if err != nil {
-		return response.CreateError(404, REGION_NOT_FOUND, err)
+		return err
 	}
-
-	db.markAsIncluded(distributor, region)
-	return successResponse
+	for distributor := range written {
+		db.index.refresh(db.store, distributor)
+	}
+	return nil
 }
 
-func (db *DataBank) markAsIncluded(distributor string, region regions.Region) {
-	db.createDistributorIfNotExists(distributor)
-
-	countryCode, provinceCode, cityCode := region.CountryCode, region.ProvinceCode, region.CityCode
-
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	switch region.Type {
-	case COUNTRY:
-		db.Distributors[distributor].includedCountries[countryCode] = true
-		delete(db.Distributors[distributor].includedProvinces, countryCode)
-		delete(db.Distributors[distributor].excludedProvinces, countryCode)
-		delete(db.Distributors[distributor].includedCities, countryCode)
-		delete(db.Distributors[distributor].excludedCities, countryCode)
-	case PROVINCE:
-		if db.Distributors[distributor].includedCountries[countryCode] {
-			if _, exists := db.Distributors[distributor].excludedProvinces[countryCode]; exists {
-				if db.Distributors[distributor].excludedProvinces[countryCode][provinceCode] {
-					delete(db.Distributors[distributor].excludedProvinces[countryCode], provinceCode)
-
-					if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-						delete(db.Distributors[distributor].includedCities[countryCode], provinceCode)
-					}
-				}
-			}
-			if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-				delete(db.Distributors[distributor].excludedCities[countryCode], provinceCode)
-			}
-			//no need to check in includedProvinces, because country is already included. so there will be only exceptions
-		} else {
-			//no need to check in excludedProvinces, because country is not included to have exceptions
-			if _, exists := db.Distributors[distributor].includedProvinces[countryCode]; exists {
-				if db.Distributors[distributor].includedProvinces[countryCode][provinceCode] {
-
-					//excudedCities are no longer excluded as the province is now included
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-						delete(db.Distributors[distributor].excludedCities[countryCode], provinceCode)
-					}
-				} else {
-					db.Distributors[distributor].includedProvinces[countryCode][provinceCode] = true
-
-					//deleting includedCities in this province as the province itself is now included as a whole
-					if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-						delete(db.Distributors[distributor].includedCities[countryCode], provinceCode)
-					}
-				}
-			} else {
-				db.Distributors[distributor].includedProvinces[countryCode] = map[string]bool{provinceCode: true}
-
-				//deleting includedCities in this province as the province itself is now included as a whole
-				if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-					delete(db.Distributors[distributor].includedCities[countryCode], provinceCode)
-				}
-				//as country or province were not there as 'included', so no need to check in excludedCities either
-			}
+// MarkInclusion includes the region in the distributor's permissions.
+// mode (ContractStrict or ContractLenient) decides whether a region held by others against exclusivity is rejected, or only reported,
+// and for a sub-distributor, whether a region its parent doesn't hold is rejected, or trimmed down to what the parent holds and reported.
+func (db *DataBank) MarkInclusion(distributor, regionString, mode string) response.Response {
+	var (
+		violations []dto.ContractViolation
+		conflicts  []dto.ExclusivityConflict
+	)
+	err := db.update(func(tx Tx) error {
+		record, ok := tx.Get(distributor)
+		if !ok {
+			return abort(response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor)))
 		}
-	case CITY:
-		if db.Distributors[distributor].includedCountries[countryCode] {
-			//there is no need to check in includedProvinces, because country is already included. so there will be only exceptions
-
-			//checking in excludedProvinces:
-			if _, exists := db.Distributors[distributor].excludedProvinces[countryCode]; exists {
-				if db.Distributors[distributor].excludedProvinces[countryCode][provinceCode] {
-
-					// if the province is excluded, then the city should be made to be included
-					if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-						if _, exists := db.Distributors[distributor].includedCities[countryCode][provinceCode]; !exists {
-							db.Distributors[distributor].includedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-						}
-					} else {
-						db.Distributors[distributor].includedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-					}
-				}
-			}
 
-			//checking in excludedCities:
-			if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-				if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-					delete(db.Distributors[distributor].excludedCities[countryCode][provinceCode], cityCode)
-				}
-			}
-		} else {
-			//as not even the country is included, we just have to check in includedProvinces+excludedCities and includedCities
-
-			//checking in includedProvinces:
-			if _, exists := db.Distributors[distributor].includedProvinces[countryCode]; exists {
-				if db.Distributors[distributor].includedProvinces[countryCode][provinceCode] {
-
-					//ensure that city is not excluded
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-						if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-							delete(db.Distributors[distributor].excludedCities[countryCode][provinceCode], cityCode)
-						}
-					}
-
-				} else {
-					//if the province is not included, then the city should be included
-					if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-						if _, exists := db.Distributors[distributor].includedCities[countryCode][provinceCode]; !exists {
-							db.Distributors[distributor].includedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-						} else {
-							db.Distributors[distributor].includedCities[countryCode][provinceCode][cityCode] = true
-						}
-					} else {
-						db.Distributors[distributor].includedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-					}
-				}
-			} else {
-
-				//if the province is not included along with country not being included, then the city should be included
-				if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].includedCities[countryCode][provinceCode]; !exists {
-						db.Distributors[distributor].includedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-					} else {
-						db.Distributors[distributor].includedCities[countryCode][provinceCode][cityCode] = true
-					}
-				} else {
-					db.Distributors[distributor].includedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-				}
-
-				//as country or province were not there as 'included', so need to check in excludedCities
-				if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-						delete(db.Distributors[distributor].excludedCities[countryCode][provinceCode], cityCode)
-					}
-				}
+		region, err := regions.GetRegionDetails(regionString)
+		if err != nil {
+			return abort(response.CreateError(404, REGION_NOT_FOUND, err))
+		}
 
+		now := time.Now().UTC()
+		included := permissions.Of(region)
+		if record.Parent != "" {
+			//the same check as for the contract of a sub-distributor, so that child ⊆ parent keeps holding
+			parent := record.Parent
+			violations = parentViolations(tx, dto.Contract{ParentDistributor: &parent, Permissions: included.DTO()}, now)
+			if len(violations) > 0 && mode != ContractLenient {
+				return exceedsParentError("inclusion", parent, violations)
+			}
+			if parentHeld, ok := getHeldPermissions(tx, parent, now); ok {
+				included = included.Intersect(parentHeld)
 			}
 		}
-	}
-}
 
-func (db *DataBank) MarkExclusion(distributor, regionString string) response.Response {
-	if !db.distributorExists(distributor) {
-		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
-	}
+		held := permissions.FromDTO(record.Permissions)
+		gained := included.Subtract(held)
+		record.Permissions = held.Union(included).DTO()
+		if record.Base != nil {
+			base := permissions.FromDTO(*record.Base).Union(included).DTO()
+			record.Base = &base
+		}
+		if parent, _ := tx.Get(record.Parent); record.timed() || parent.timed() {
+			record, _ = materialize(tx, record, now)
+		}
+		tx.Put(distributor, record)
 
-	region, err := regions.GetRegionDetails(regionString)
+		conflicts = exclusivityConflicts(tx, distributor, gained, record.Exclusive, now)
+		if len(conflicts) > 0 && mode != ContractLenient {
+			return exclusivityConflictError(distributor, conflicts)
+		}
+		return nil
+	})
 	if err != nil {
-		return response.CreateError(404, REGION_NOT_FOUND, err)
+		return transactionErrorResponse(err)
 	}
 
-	db.markAsExcluded(distributor, region)
+	data := map[string]interface{}{}
+	if len(violations) > 0 {
+		data["warnings"] = violations
+	}
+	if len(conflicts) > 0 {
+		data["conflicts"] = conflicts
+	}
+	if len(data) > 0 {
+		return response.CreateSuccess(200, "SUCCESS", data)
+	}
 	return successResponse
 }
 
-func (db *DataBank) markAsExcluded(distributor string, region regions.Region) {
-	db.createDistributorIfNotExists(distributor)
-
-	countryCode, provinceCode, cityCode := region.CountryCode, region.ProvinceCode, region.CityCode
-
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	switch region.Type {
-	case COUNTRY:
-		delete(db.Distributors[distributor].includedCountries, countryCode)
-		delete(db.Distributors[distributor].includedProvinces, countryCode)
-		delete(db.Distributors[distributor].includedCities, countryCode)
-		delete(db.Distributors[distributor].excludedProvinces, countryCode)
-		delete(db.Distributors[distributor].excludedCities, countryCode)
-
-	case PROVINCE:
-		if db.Distributors[distributor].includedCountries[countryCode] {
-			//if the country is included, then the province should be excluded
-			if _, exists := db.Distributors[distributor].excludedProvinces[countryCode]; exists {
-				db.Distributors[distributor].excludedProvinces[countryCode][provinceCode] = true
-			} else {
-				db.Distributors[distributor].excludedProvinces[countryCode] = map[string]bool{provinceCode: true}
-			}
-
-			//as the province as a whole is excluded, then the cities in the province need not be in excluded list
-			if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-				delete(db.Distributors[distributor].excludedCities[countryCode], provinceCode)
-			}
-		} else {
-			//if the country is not included, then the province should not be in included list
-			if _, exists := db.Distributors[distributor].includedProvinces[countryCode]; exists {
-				if db.Distributors[distributor].includedProvinces[countryCode][provinceCode] {
-					delete(db.Distributors[distributor].includedProvinces[countryCode], provinceCode)
-
-					//as the province is excluded, then the cities in the province need not be in excluded list
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-						delete(db.Distributors[distributor].excludedCities[countryCode], provinceCode)
-					}
-				}
-			}
-
-			//ensure that no city in the province is in included list
-			if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-				delete(db.Distributors[distributor].includedCities[countryCode], provinceCode)
-			}
-
+func (db *DataBank) MarkExclusion(distributor, regionString string) response.Response {
+	var affected []string
+	err := db.update(func(tx Tx) error {
+		record, ok := tx.Get(distributor)
+		if !ok {
+			return abort(response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor)))
 		}
 
-	case CITY:
-		if db.Distributors[distributor].includedCountries[countryCode] {
-			//check if the province is excluded
-			if _, exists := db.Distributors[distributor].excludedProvinces[countryCode]; exists && db.Distributors[distributor].excludedProvinces[countryCode][provinceCode] {
-				//if the province is excluded, then the city should not be in included list
-				if _, exists := db.Distributors[distributor].includedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].includedCities[countryCode][provinceCode]; exists {
-						delete(db.Distributors[distributor].includedCities[countryCode][provinceCode], cityCode)
-					}
-				}
-			} else { //if the province is not excluded, then the city should be in excluded list
-				if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode][cityCode] = true
-					} else {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-					}
-				} else {
-					db.Distributors[distributor].excludedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-				}
-			}
-		} else { //if the country is not included, then either (the city should be in excluded list) or (the province should be in excluded list with no exception for the city)
-			if _, exists := db.Distributors[distributor].excludedProvinces[countryCode]; exists && db.Distributors[distributor].excludedProvinces[countryCode][provinceCode] {
-				if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode][cityCode] = true
-					} else {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-					}
-				} else {
-					db.Distributors[distributor].excludedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-				}
-			} else { //if the province is not excluded, then the city should be in excluded list
-				if _, exists := db.Distributors[distributor].excludedCities[countryCode]; exists {
-					if _, exists := db.Distributors[distributor].excludedCities[countryCode][provinceCode]; exists {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode][cityCode] = true
-					} else {
-						db.Distributors[distributor].excludedCities[countryCode][provinceCode] = map[string]bool{cityCode: true}
-					}
-				} else {
-					db.Distributors[distributor].excludedCities[countryCode] = map[string]map[string]bool{provinceCode: {cityCode: true}}
-				}
-			}
+		region, err := regions.GetRegionDetails(regionString)
+		if err != nil {
+			return abort(response.CreateError(404, REGION_NOT_FOUND, err))
+		}
 
+		record.Permissions = permissions.FromDTO(record.Permissions).Exclude(region).DTO()
+		if record.timed() {
+			record = excludeFromTimedPermissions(record, region)
 		}
+		tx.Put(distributor, record)
 
+		affected = cascadeToDescendants(tx, distributor, time.Now().UTC())
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
 	}
-
+	return cascadeResponse(affected)
 }
 
 func (db *DataBank) AddDistributor(distributor string) response.Response {
-	if db.distributorExists(distributor) {
-		return response.CreateError(400, "DISTRIBUTOR_EXISTS", ErrDistributorExists)
+	err := db.update(func(tx Tx) error {
+		if _, exists := tx.Get(distributor); exists {
+			return abort(response.CreateError(400, "DISTRIBUTOR_EXISTS", ErrDistributorExists))
+		}
+		tx.Put(distributor, Record{Permissions: permissions.New().DTO()})
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
 	}
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	db.Distributors[distributor] = newPermissionData()
 	return createdResponse
 }
 
-func (db *DataBank) RemoveDistributor(distributor string) response.Response {
-	if !db.distributorExists(distributor) {
-		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, ErrDistributorNotFound)
-	}
-
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	delete(db.Distributors, distributor)
-
-	return successResponse
-}
-
-func (db *DataBank) isAllowedForTheDistributor(distributor string, region regions.Region) (bool, string) {
-	const (
-		PARTIALLY_ALLOWED = "PARTIALLY_ALLOWED"
-		FULLY_ALLOWED     = "FULLY_ALLOWED"
-		FULLY_DENIED      = "FULLY_DENIED"
-	)
-	db.mu.RLock()
-	defer db.mu.RUnlock()
-	permissionData, ok := db.Distributors[distributor]
-	if !ok {
-		return false, ""
-	}
-
-	countryCode, provinceCode, cityCode, regionType := region.CountryCode, region.ProvinceCode, region.CityCode, region.Type
+// RemoveDistributor removes the distributor, handling its sub-distributors as per the removal policy:
+//   - RemovalRestrict: fails if the distributor has sub-distributors
+//   - RemovalCascade: removes the whole subtree along with the distributor
+//   - RemovalOrphanRevoke: keeps the sub-distributors, but strips all the rights they got through the distributor
+//     (its children become top-level distributors with no permissions, and so do their descendants' permissions)
+//
+// The policy applies to the hierarchy of every film too. The sub-distributors that lose only their rights on a film,
+// whether removed from its hierarchy or stripped of them, are reported per film apart from the ones removed or revoked on the whole catalog.
+func (db *DataBank) RemoveDistributor(distributor, policy string) response.Response {
+	var removed, revoked []string
+	revokedOnFilms := make(map[string][]string)
+
+	err := db.update(func(tx Tx) error {
+		if _, exists := tx.Get(distributor); !exists {
+			return abort(response.CreateError(404, DISTRIBUTOR_NOT_FOUND, ErrDistributorNotFound))
+		}
 
-	switch regionType {
-	case COUNTRY:
-		if permissionData.includedCountries[countryCode] {
-			if len(permissionData.excludedProvinces[countryCode]) > 0 {
-				return false, PARTIALLY_ALLOWED
-			}
-			if len(permissionData.excludedCities[countryCode]) > 0 {
-				return false, PARTIALLY_ALLOWED
+		//the films first, as removing the distributor from the whole catalog removes its rights on every film along with it
+		scopes := filmScopes(tx)
+		slices.Reverse(scopes)
+		for _, film := range scopes {
+			scoped, _ := scopeTx(tx, film)
+			scopeRemoved, scopeRevoked, err := removeFromScope(scoped, distributor, policy)
+			if err != nil {
+				return err
 			}
-			return true, FULLY_ALLOWED
-		} else {
-			if len(permissionData.includedProvinces[countryCode]) > 0 {
-				return false, PARTIALLY_ALLOWED
+			if film == "" {
+				removed, revoked = scopeRemoved, scopeRevoked
+				continue
 			}
-			for provinceCode := range permissionData.includedCities[countryCode] {
-				if len(permissionData.includedCities[countryCode][provinceCode]) > 0 {
-					return false, PARTIALLY_ALLOWED
-				}
+			//the distributor itself is removed from the whole catalog
+			if onFilm := appendMissing(scopeRemoved[1:], scopeRevoked...); len(onFilm) > 0 {
+				revokedOnFilms[film] = onFilm
 			}
-			return false, FULLY_DENIED
 		}
-	case PROVINCE:
-		if permissionData.includedCountries[countryCode] {
-			if _, exists := permissionData.excludedProvinces[countryCode]; exists && permissionData.excludedProvinces[countryCode][provinceCode] {
-				return false, FULLY_DENIED
-			}
-			if _, exists := permissionData.excludedCities[countryCode]; exists && len(permissionData.excludedCities[countryCode][provinceCode]) > 0 {
-				return false, PARTIALLY_ALLOWED
-			}
-			return true, FULLY_ALLOWED
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+
+	//the sub-distributors removed from the whole catalog have no rights left to report on films
+	for film, onFilm := range revokedOnFilms {
+		onFilm = slices.DeleteFunc(onFilm, func(descendant string) bool { return slices.Contains(removed, descendant) })
+		if len(onFilm) == 0 {
+			delete(revokedOnFilms, film)
 		} else {
-			if _, exists := permissionData.includedProvinces[countryCode]; exists && permissionData.includedProvinces[countryCode][provinceCode] {
-				if _, exists := permissionData.excludedCities[countryCode]; exists && len(permissionData.excludedCities[countryCode][provinceCode]) > 0 {
-					return false, PARTIALLY_ALLOWED
-				}
-				return true, FULLY_ALLOWED
-			} else {
-				if _, exists := permissionData.includedCities[countryCode]; exists {
-					if _, exists := permissionData.includedCities[countryCode][provinceCode]; exists && len(permissionData.includedCities[countryCode][provinceCode]) > 0 {
-						return false, PARTIALLY_ALLOWED
-					}
-				}
-			}
+			revokedOnFilms[film] = onFilm
 		}
-	case CITY:
-		if permissionData.includedCountries[countryCode] {
-			if _, exists := permissionData.excludedProvinces[countryCode]; exists && permissionData.excludedProvinces[countryCode][provinceCode] {
-				return false, FULLY_DENIED
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"removed":          removed,
+		"revoked":          revoked,
+		"revoked_on_films": revokedOnFilms,
+	})
+}
+
+// removeFromScope removes the distributor from the hierarchy of the scope (the whole catalog, or a film), as per the removal policy.
+func removeFromScope(tx Tx, distributor, policy string) (removed, revoked []string, err error) {
+	removed = []string{distributor}
+	revoked = []string{}
+
+	children := getChildren(tx, distributor)
+	switch policy {
+	case RemovalRestrict:
+		if len(children) > 0 {
+			resp := response.CreateError(409, "DISTRIBUTOR_HAS_CHILDREN", fmt.Errorf("distributor %s has sub-distributors: %s", distributor, strings.Join(children, ", ")))
+			resp.Data = map[string]interface{}{
+				"children": children,
 			}
-			if _, exists := permissionData.excludedCities[countryCode]; exists {
-				if _, exists := permissionData.excludedCities[countryCode][provinceCode]; exists && permissionData.excludedCities[countryCode][provinceCode][cityCode] {
-					return false, FULLY_DENIED
-				}
-				return true, FULLY_ALLOWED
-			} else {
-				if _, exists := permissionData.includedProvinces[countryCode]; exists && permissionData.includedProvinces[countryCode][provinceCode] {
-					if _, exists := permissionData.excludedCities[countryCode]; exists {
-						if _, exists := permissionData.excludedCities[countryCode][provinceCode]; exists && permissionData.excludedCities[countryCode][provinceCode][cityCode] {
-							return false, FULLY_DENIED
-						}
-					}
-					return true, FULLY_ALLOWED
-				}
-				if _, exists := permissionData.includedCities[countryCode]; exists {
-					if _, exists := permissionData.includedCities[countryCode][provinceCode]; exists && permissionData.includedCities[countryCode][provinceCode][cityCode] {
-						return true, FULLY_ALLOWED
-					}
-				}
-				return false, FULLY_DENIED
+			return nil, nil, abort(resp)
+		}
+	case RemovalCascade:
+		for _, descendant := range getDescendants(tx, distributor) {
+			tx.Delete(descendant)
+			removed = append(removed, descendant)
+		}
+	case RemovalOrphanRevoke:
+		for _, descendant := range getDescendants(tx, distributor) {
+			record, _ := tx.Get(descendant)
+			if record.Parent == distributor {
+				record.Parent = ""
 			}
+			record.Permissions = permissions.New().DTO()
+			record.Base, record.Grants = nil, nil
+			record.Exclusive = false
+			tx.Put(descendant, record)
+			revoked = append(revoked, descendant)
 		}
+	default:
+		return nil, nil, abort(response.CreateError(400, "INVALID_REMOVAL_POLICY", fmt.Errorf("unknown removal policy: %s", policy)))
 	}
 
-	return false, "UNKNOWN" //this should never happen, as the region type is already validated
+	tx.Delete(distributor)
+	return removed, revoked, nil
 }
 
-func (db *DataBank) GetDistributors() response.Response {
-	db.mu.RLock()
-	defer db.mu.RUnlock()
-	distributors := make([]string, 0, len(db.Distributors))
-	for distributor := range db.Distributors {
-		distributors = append(distributors, distributor)
+// appendMissing appends the values that are not in the slice yet.
+func appendMissing(slice []string, values ...string) []string {
+	if slice == nil {
+		slice = []string{}
 	}
+	for _, value := range values {
+		if !slices.Contains(slice, value) {
+			slice = append(slice, value)
+		}
+	}
+	return slice
+}
+
+func isAllowed(reader recordReader, distributor string, region regions.Region) (bool, string) {
+	record, ok := reader.Get(distributor)
+	if !ok {
+		return false, ""
+	}
+	status := permissions.FromDTO(record.Permissions).Decide(region)
+	return status == FULLY_ALLOWED, status
+}
+
+func (db *DataBank) GetDistributors() response.Response {
+	distributors := db.store.List()
+	sort.Strings(distributors)
 	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
 		"distributors": distributors,
 	})
 }
 
-func (db *DataBank) CheckIfDistributionIsAllowed(distributor, regionString string) response.Response {
+// CheckIfDistributionIsAllowed tells whether the distributor can distribute in the region at the given time,
+// counting in only the time-bound contracts valid at that time. With a film, the rights on the film are checked instead of the ones on the whole catalog.
+func (db *DataBank) CheckIfDistributionIsAllowed(distributor, regionString, film string, at time.Time) response.Response {
+	var resp response.Response
+	db.store.View(func(view ReadTx) {
+		scoped, ok := scopeView(view, film)
+		if !ok {
+			resp = filmNotFoundResponse(film)
+			return
+		}
+		resp = checkPermissionAt(scoped, distributor, regionString, at)
+	})
+	return resp
+}
+
+// CheckIfDistributionWasAllowed tells whether the distributor could distribute in the region at a past instant,
+// reconstructing the permissions it held then from the permission history.
+func (db *DataBank) CheckIfDistributionWasAllowed(distributor, regionString, film string, asOf time.Time) response.Response {
+	var resp response.Response
+	db.store.View(func(view ReadTx) {
+		scoped, ok := scopeView(view, film)
+		if !ok {
+			resp = filmNotFoundResponse(film)
+			return
+		}
+		resp = checkPermissionAt(asOfReader{reader: scoped, at: asOf}, distributor, regionString, asOf)
+	})
+	return resp
+}
+
+func checkPermissionAt(reader recordReader, distributor, regionString string, at time.Time) response.Response {
 	region, err := regions.GetRegionDetails(regionString)
 	if err != nil {
 		return response.CreateError(404, REGION_NOT_FOUND, err)
 	}
 
-	if !db.distributorExists(distributor) {
+	held, exists := permissionsAt(reader, distributor, at)
+	if !exists {
 		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
 	}
 
-	_, status := db.isAllowedForTheDistributor(distributor, region)
-	return response.CreateSuccess(200, status, nil)
+	return response.CreateSuccess(200, held.Decide(region), nil)
 }
 
 func (db *DataBank) distributorExists(distributor string) bool {
-	db.mu.RLock()
-	defer db.mu.RUnlock()
-	if _, ok := db.Distributors[distributor]; ok {
-		return true
-	}
-	return false
+	_, ok := db.store.Get(distributor)
+	return ok
 }
 
 // func (db *DataBank) getParentRegions(distributor string) ([]regions.Region, []regions.Region) {
 // 	return nil, nil
 // }
 
-func (db *DataBank) getDistributorPermissionCopy(distributor string) (permissionData, bool) {
-	db.mu.RLock()
-	defer db.mu.RUnlock()
-	if permissionData, ok := db.Distributors[distributor]; ok {
-		return permissionData.copyPermissionData(), true
+func (db *DataBank) getDistributorPermissionCopy(distributor string) (permissions.PermissionSet, bool) {
+	return getPermissionCopy(db.store, distributor)
+}
+
+// getPermissionCopy returns a copy of the distributor's permissions that the caller is free to modify.
+func getPermissionCopy(reader recordReader, distributor string) (permissions.PermissionSet, bool) {
+	if record, ok := reader.Get(distributor); ok {
+		return permissions.FromDTO(record.Permissions).Copy(), true
 	}
-	return permissionData{}, false
+	return permissions.PermissionSet{}, false
 }
 
-func (db *DataBank) GetDistributorPermissionsAsText(distributor string) string {
-	permissionData, ok := db.getDistributorPermissionCopy(distributor)
+// GetDistributorPermissionsAsText returns the permissions of the distributor as contract text: the current ones if asOf is nil,
+// or the ones it held at asOf. With a film, its rights on the film are returned instead of the ones on the whole catalog.
+func (db *DataBank) GetDistributorPermissionsAsText(distributor, film string, asOf *time.Time) string {
+	var (
+		held           permissions.PermissionSet
+		lineage        []string
+		filmExists, ok bool
+	)
+	db.store.View(func(view ReadTx) {
+		var scoped ReadTx
+		if scoped, filmExists = scopeView(view, film); filmExists {
+			held, lineage, ok = permissionsAsOf(scoped, distributor, asOf)
+		}
+	})
+	if !filmExists {
+		return "Film not found"
+	}
 	if !ok {
 		return "Distributor not found"
 	}
 
 	builder := new(strings.Builder)
-	builder.WriteString("Permissions for " + distributor)
-
-	for country := range permissionData.includedCountries {
-		builder.WriteString("\nINCLUDE: " + country)
-	}
-	for country := range permissionData.includedProvinces {
-		for province := range permissionData.includedProvinces[country] {
-			builder.WriteString("\nINCLUDE: " + province + "-" + country)
-		}
-	}
-	for country := range permissionData.includedCities {
-		for province := range permissionData.includedCities[country] {
-			for city := range permissionData.includedCities[country][province] {
-				builder.WriteString("\nINCLUDE: " + city + "-" + province + "-" + country)
-			}
-		}
+	builder.WriteString("Permissions for " + strings.Join(lineage, " < "))
+	if film != "" {
+		builder.WriteString(" ON FILM:" + film)
 	}
 
-	for country := range permissionData.excludedProvinces {
-		for province := range permissionData.excludedProvinces[country] {
-			builder.WriteString("\nEXCLUDE: " + province + "-" + country)
-		}
+	//the permissions of the past are computed from the history, which may predate normalization
+	inclusions, exclusions := held.Normalize().RegionLists()
+	for _, inclusion := range inclusions {
+		builder.WriteString("\nINCLUDE: " + inclusion)
 	}
-	for country := range permissionData.excludedCities {
-		for province := range permissionData.excludedCities[country] {
-			for city := range permissionData.excludedCities[country][province] {
-				builder.WriteString("\nEXCLUDE: " + city + "-" + province + "-" + country)
-			}
-		}
+	for _, exclusion := range exclusions {
+		builder.WriteString("\nEXCLUDE: " + exclusion)
 	}
 
 	return builder.String()
 }
 
-func (db *DataBank) GetDistributorPermissionAsJSON(distributor string) response.Response {
-	permissionData, ok := db.getDistributorPermissionCopy(distributor)
-	if !ok {
-		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
-	}
-
-	inclusions := make([]string, 0, len(permissionData.includedCountries)+len(permissionData.includedProvinces)+len(permissionData.includedCities))
-	exclusions := make([]string, 0, len(permissionData.excludedProvinces)+len(permissionData.excludedCities))
-
-	for country := range permissionData.includedCountries {
-		inclusions = append(inclusions, country)
-	}
-	for country := range permissionData.includedProvinces {
-		for province := range permissionData.includedProvinces[country] {
-			inclusions = append(inclusions, province+"-"+country)
-		}
-	}
-	for country := range permissionData.includedCities {
-		for province := range permissionData.includedCities[country] {
-			for city := range permissionData.includedCities[country][province] {
-				inclusions = append(inclusions, city+"-"+province+"-"+country)
-			}
+// GetDistributorPermissionAsJSON returns the permissions of the distributor: the current ones if asOf is nil, or the ones it held at asOf.
+// With a film, its rights on the film are returned instead of the ones on the whole catalog.
+func (db *DataBank) GetDistributorPermissionAsJSON(distributor, film string, asOf *time.Time) response.Response {
+	var (
+		held           permissions.PermissionSet
+		filmExists, ok bool
+	)
+	db.store.View(func(view ReadTx) {
+		var scoped ReadTx
+		if scoped, filmExists = scopeView(view, film); filmExists {
+			held, _, ok = permissionsAsOf(scoped, distributor, asOf)
 		}
+	})
+	if !filmExists {
+		return filmNotFoundResponse(film)
 	}
-
-	for country := range permissionData.excludedProvinces {
-		for province := range permissionData.excludedProvinces[country] {
-			exclusions = append(exclusions, province+"-"+country)
-		}
+	if !ok {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
 	}
 
-	for country := range permissionData.excludedCities {
-		for province := range permissionData.excludedCities[country] {
-			for city := range permissionData.excludedCities[country][province] {
-				exclusions = append(exclusions, city+"-"+province+"-"+country)
-			}
-		}
-	}
+	inclusions, exclusions := held.Normalize().RegionLists()
 
 	data:=dto.GetPermissionsData{
 		Distributor: distributor,
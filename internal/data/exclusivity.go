@@ -0,0 +1,78 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/response"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	EXCLUSIVITY_CONFLICT = "EXCLUSIVITY_CONFLICT"
+)
+
+/*
+Exclusivity.
+
+A distributor that got an exclusive contract (EXCLUSIVE: YES) holds its rights exclusively: no distributor outside its lineage
+(its ancestors and its descendants, who naturally share its regions) may hold any of its regions.
+When a distributor gains regions, they are checked against the rights of every exclusive holder, or of every distributor if it holds
+its own rights exclusively. Conflicts reject the change in strict mode, and are reported along with it in lenient mode.
+*/
+
+// exclusivityConflicts returns the regions of gained that distributors outside the lineage of the distributor hold (or will hold, as of now):
+// all of them if exclusive is set, only the exclusive holders otherwise.
+func exclusivityConflicts(lister recordLister, distributor string, gained permissions.PermissionSet, exclusive bool, now time.Time) []dto.ExclusivityConflict {
+	conflicts := []dto.ExclusivityConflict{}
+	if gained.IsEmpty() {
+		return conflicts
+	}
+
+	related := map[string]bool{}
+	lineage, _ := getLineage(lister, distributor)
+	for _, relative := range append(lineage, getDescendants(lister, distributor)...) {
+		related[relative] = true
+	}
+
+	for _, other := range sortedDistributors(lister) {
+		if related[other] {
+			continue
+		}
+		record, _ := lister.Get(other)
+		if !exclusive && !record.Exclusive {
+			continue
+		}
+		overlap := gained.Intersect(record.heldPermissions(now))
+		if overlap.IsEmpty() {
+			continue
+		}
+		conflicts = append(conflicts, dto.ExclusivityConflict{
+			Distributor: other,
+			Exclusive:   record.Exclusive,
+			Overlap:     toRegionLists(overlap),
+		})
+	}
+	return conflicts
+}
+
+func sortedDistributors(lister recordLister) []string {
+	distributors := lister.List()
+	sort.Strings(distributors)
+	return distributors
+}
+
+// exclusivityConflictError is the error rejecting a change because of the conflicts, in strict mode.
+func exclusivityConflictError(distributor string, conflicts []dto.ExclusivityConflict) error {
+	descriptions := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		descriptions[i] = conflict.Distributor + " (" + strings.Join(conflict.Overlap.Included, ", ") + ")"
+	}
+	resp := response.CreateError(409, EXCLUSIVITY_CONFLICT, fmt.Errorf("%s would hold regions held by other distributors against exclusivity: %s", distributor, strings.Join(descriptions, "; ")))
+	resp.Data = map[string]interface{}{
+		"conflicts": conflicts,
+	}
+	return abort(resp)
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"fmt"
+)
+
+const (
+	PARTIALLY_ALLOWED = permissions.PARTIALLY_ALLOWED
+	FULLY_ALLOWED     = permissions.FULLY_ALLOWED
+	FULLY_DENIED      = permissions.FULLY_DENIED
+)
+
+// ExplainPermission returns the decision on the region for the distributor along with the rules behind it,
+// and for sub-distributors, the decisions of its ancestors.
+func (db *DataBank) ExplainPermission(distributor, regionString string) response.Response {
+	region, err := regions.GetRegionDetails(regionString)
+	if err != nil {
+		return response.CreateError(404, REGION_NOT_FOUND, err)
+	}
+
+	var (
+		explanation dto.ExplainPermissionData
+		exists      bool
+	)
+	db.store.View(func(view ReadTx) {
+		var lineage []string
+		if lineage, exists = getLineage(view, distributor); !exists {
+			return
+		}
+
+		held, _ := getPermissionCopy(view, distributor)
+		explanation = dto.ExplainPermissionData{
+			Distributor: distributor,
+			Region:      regionString,
+			Decision:    held.Decide(region),
+			Rules:       held.MatchingRules(region),
+			Exceptions:  held.ExceptionRules(region),
+		}
+
+		for _, ancestor := range lineage[1:] {
+			ancestorPermissions, ok := getPermissionCopy(view, ancestor)
+			if !ok {
+				break
+			}
+			explanation.Ancestors = append(explanation.Ancestors, dto.AncestorDecision{
+				Distributor: ancestor,
+				Decision:    ancestorPermissions.Decide(region),
+				Rules:       ancestorPermissions.MatchingRules(region),
+				Exceptions:  ancestorPermissions.ExceptionRules(region),
+			})
+		}
+	})
+	if !exists {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+
+	if explanation.Decision != FULLY_ALLOWED {
+		for i := len(explanation.Ancestors) - 1; i >= 0; i-- {
+			if explanation.Ancestors[i].Decision != FULLY_ALLOWED {
+				explanation.RestrictedBy = &explanation.Ancestors[i]
+				break
+			}
+		}
+	}
+
+	return response.CreateSuccess(200, "SUCCESS", explanation)
+}
@@ -0,0 +1,350 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"challenge16/internal/permissions"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+type (
+	// walRecord is one entry of the write-ahead log: the writes of one transaction.
+	// It carries the resulting record of each distributor written,
+	// so that replaying doesn't depend on the region catalog or on the merge logic of the version that wrote it,
+	// and only the entries appended to the journals, whose size would otherwise grow with every change.
+	walRecord struct {
+		Seq      uint64             `json:"seq"`
+		Changes  map[string]*Record `json:"changes"`            //nil when the distributor is removed
+		Films    map[string]*Film   `json:"films,omitempty"`    //nil when the film is removed
+		Appended *journals          `json:"appended,omitempty"` //entries appended to the journals
+	}
+
+	// snapshot is the compacted state of the DataBank after applying every log entry up to Seq.
+	snapshot struct {
+		Seq          uint64            `json:"seq"`
+		Distributors map[string]Record `json:"distributors"`
+		Films        map[string]Film   `json:"films,omitempty"`
+		Journals     journals          `json:"journals"`
+	}
+
+	// fileStore is a memoryStore whose transactions are appended to a write-ahead log before being applied.
+	fileStore struct {
+		memoryStore
+
+		dir              string
+		wal              *os.File
+		walSize          int64
+		seq              uint64
+		sinceSnapshot    int
+		snapshotInterval int
+	}
+)
+
+/*
+Log line format:
+	<crc32 of json, 8 hex digits> <json>\n
+
+A line that is incomplete or whose checksum doesn't match can only be the result of a crash in the middle of a write,
+so replay stops there and the log is truncated back to the last complete entry.
+*/
+
+// OpenFileStore opens the Store persisted in dir, restoring the state left by previous runs.
+// Every transaction is appended to a write-ahead log, which is compacted into a snapshot every snapshotInterval transactions (0 disables compaction).
+func OpenFileStore(dir string, snapshotInterval int) (Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating data directory: %w", err)
+	}
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening write-ahead log: %w", err)
+	}
+
+	s := &fileStore{
+		memoryStore: memoryStore{
+			distributors: snap.Distributors,
+			films:        snap.Films,
+			journals:     snap.Journals,
+		},
+		dir:              dir,
+		wal:              wal,
+		seq:              snap.Seq,
+		snapshotInterval: snapshotInterval,
+	}
+
+	if err := s.replay(); err != nil {
+		wal.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	snap := snapshot{Distributors: make(map[string]Record), Films: make(map[string]Film), Journals: newJournals()}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, fmt.Errorf("error reading snapshot: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return snap, fmt.Errorf("error parsing snapshot: %w", err)
+	}
+	if snap.Films == nil {
+		snap.Films = make(map[string]Film) //snapshot written before films were kept
+	}
+	if snap.Journals.History == nil {
+		snap.Journals.History = make(map[string][]PermissionChange)
+	}
+	if snap.Journals.Versions == nil {
+		snap.Journals.Versions = make(map[string][]ContractVersion)
+	}
+	for distributor, record := range snap.Distributors {
+		record.Permissions = permissions.FromDTO(record.Permissions).DTO() //fill in maps that were null
+		snap.Distributors[distributor] = record
+	}
+	return snap, nil
+}
+
+// replay applies the log entries that are not yet part of the snapshot.
+func (s *fileStore) replay() error {
+	reader := bufio.NewReader(s.wal)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break //a partial last line (if any) is dropped below
+		}
+		if err != nil {
+			return fmt.Errorf("error reading write-ahead log: %w", err)
+		}
+
+		record, ok := decodeWALLine(line)
+		if !ok {
+			break
+		}
+		offset += int64(len(line))
+
+		if record.Seq <= s.seq {
+			continue //already part of the snapshot (crash after snapshotting, before truncating the log)
+		}
+		s.seq = record.Seq
+		s.sinceSnapshot++
+
+		for distributor, change := range record.Changes {
+			if change == nil {
+				delete(s.distributors, distributor)
+			} else {
+				change.Permissions = permissions.FromDTO(change.Permissions).DTO()
+				s.distributors[distributor] = *change
+			}
+		}
+		for id, change := range record.Films {
+			if change == nil {
+				delete(s.films, id)
+			} else {
+				s.films[id] = *change
+			}
+		}
+		if record.Appended != nil {
+			record.Appended.appendTo(s.journals)
+		}
+	}
+
+	//discard whatever follows the last complete entry
+	if err := s.wal.Truncate(offset); err != nil {
+		return fmt.Errorf("error truncating write-ahead log: %w", err)
+	}
+	s.walSize = offset
+	return nil
+}
+
+func decodeWALLine(line []byte) (walRecord, bool) {
+	var record walRecord
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	checksumText, payload, found := bytes.Cut(line, []byte(" "))
+	if !found {
+		return record, false
+	}
+	checksum, err := strconv.ParseUint(string(checksumText), 16, 32)
+	if err != nil || uint32(checksum) != crc32.ChecksumIEEE(payload) {
+		return record, false
+	}
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return record, false
+	}
+	return record, true
+}
+
+func (s *fileStore) Put(distributor string, record Record) error {
+	return s.Update(func(tx Tx) error {
+		tx.Put(distributor, record)
+		return nil
+	})
+}
+
+func (s *fileStore) Delete(distributor string) error {
+	return s.Update(func(tx Tx) error {
+		tx.Delete(distributor)
+		return nil
+	})
+}
+
+func (s *fileStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := newTransaction(s.distributors, s.films, s.journals)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tx.empty() {
+		return nil
+	}
+
+	//write-ahead: the transaction is applied only once it is durable
+	entry := &walRecord{Changes: tx.changes, Films: tx.filmChanges}
+	if !tx.appended.empty() {
+		entry.Appended = &tx.appended
+	}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	tx.applyTo(s.distributors, s.films, s.journals)
+
+	if s.snapshotDue() {
+		if err := s.writeSnapshot(); err != nil {
+			//not fatal, the log still has every entry. Compaction will be retried after the next transaction
+			log.Println("error writing snapshot:", err)
+		}
+	}
+	return nil
+}
+
+func (s *fileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wal.Close()
+}
+
+// append writes the record to the log and syncs it to disk.
+func (s *fileStore) append(record *walRecord) error {
+	record.Seq = s.seq + 1
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error encoding log entry: %w", err)
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+
+	n, err := s.wal.WriteString(line)
+	if err == nil {
+		err = s.wal.Sync()
+	}
+	if err != nil {
+		if n > 0 {
+			//don't leave a partial entry behind for the next append to follow
+			_ = s.wal.Truncate(s.walSize)
+		}
+		return fmt.Errorf("error writing log entry: %w", err)
+	}
+
+	s.walSize += int64(n)
+	s.seq = record.Seq
+	s.sinceSnapshot++
+	return nil
+}
+
+func (s *fileStore) snapshotDue() bool {
+	return s.snapshotInterval > 0 && s.sinceSnapshot >= s.snapshotInterval
+}
+
+// writeSnapshot compacts the current state into the snapshot file and empties the log.
+// The snapshot is written to a temporary file and renamed over the old one, so a crash leaves either the old or the new snapshot intact.
+func (s *fileStore) writeSnapshot() error {
+	snap := snapshot{
+		Seq:          s.seq,
+		Distributors: s.distributors,
+		Films:        s.films,
+		Journals:     s.journals,
+	}
+
+	content, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("error encoding snapshot: %w", err)
+	}
+
+	path := filepath.Join(s.dir, snapshotFileName)
+	tmpPath := path + ".tmp"
+	if err := writeFileSynced(tmpPath, content); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error replacing snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+
+	//entries up to snap.Seq are now in the snapshot
+	if err := s.wal.Truncate(0); err != nil {
+		return fmt.Errorf("error truncating write-ahead log: %w", err)
+	}
+	if err := s.wal.Sync(); err != nil {
+		return fmt.Errorf("error syncing write-ahead log: %w", err)
+	}
+	s.walSize = 0
+	s.sinceSnapshot = 0
+	return nil
+}
+
+func writeFileSynced(path string, content []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error syncing %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("error opening data directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("error syncing data directory: %w", err)
+	}
+	return nil
+}
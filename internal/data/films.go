@@ -0,0 +1,264 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/response"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	FILM_NOT_FOUND = "FILM_NOT_FOUND"
+)
+
+/*
+Rights per film.
+
+A distributor's Record holds its rights on the whole catalog, and in Films, a Record for each film it has rights on through
+contracts headed "... ON FILM:<id>". Each film is a scope of its own, with its own hierarchy, versions, history, and time-bound grants:
+filmTx shows the records and journals of one film as if they were the ones of the store, so that everything working on a Tx or reader
+works within a film as it does on the whole catalog, parent filtering included.
+*/
+
+// journalKey returns the key of the journals of the distributor on the film, or on the whole catalog for film "".
+func journalKey(distributor, film string) string {
+	if film == "" {
+		return distributor
+	}
+	return distributor + " ON FILM:" + film
+}
+
+// filmTx is the view of a transaction on the rights for one film.
+// Every distributor of the store is seen, with no rights if it has none on the film, but List returns only the ones with rights on it.
+type filmTx struct {
+	Tx
+	film string
+}
+
+func (tx *filmTx) Get(distributor string) (Record, bool) {
+	return filmRecord(tx.Tx, distributor, tx.film)
+}
+
+func (tx *filmTx) List() []string {
+	return filmDistributors(tx.Tx, tx.film)
+}
+
+func (tx *filmTx) Put(distributor string, record Record) {
+	base, exists := tx.Tx.Get(distributor)
+	if !exists {
+		base = Record{Permissions: permissions.New().DTO()}
+	}
+	record.Films = nil
+	films := maps.Clone(base.Films) //the stored map is shared, it must not be modified in place
+	if films == nil {
+		films = make(map[string]Record)
+	}
+	films[tx.film] = record
+	base.Films = films
+	tx.Tx.Put(distributor, base)
+}
+
+func (tx *filmTx) Delete(distributor string) {
+	base, exists := tx.Tx.Get(distributor)
+	if _, onFilm := base.Films[tx.film]; !exists || !onFilm {
+		return
+	}
+	films := maps.Clone(base.Films)
+	delete(films, tx.film)
+	if len(films) == 0 {
+		films = nil
+	}
+	base.Films = films
+	tx.Tx.Put(distributor, base)
+}
+
+func (tx *filmTx) History(distributor string) []PermissionChange {
+	return tx.Tx.History(journalKey(distributor, tx.film))
+}
+
+func (tx *filmTx) AppendHistory(distributor string, change PermissionChange) {
+	tx.Tx.AppendHistory(journalKey(distributor, tx.film), change)
+}
+
+func (tx *filmTx) Versions(distributor string) []ContractVersion {
+	return tx.Tx.Versions(journalKey(distributor, tx.film))
+}
+
+func (tx *filmTx) AppendVersion(distributor string, version ContractVersion) {
+	tx.Tx.AppendVersion(journalKey(distributor, tx.film), version)
+}
+
+// filmView is the read-only counterpart of filmTx.
+type filmView struct {
+	ReadTx
+	film string
+}
+
+func (view filmView) Get(distributor string) (Record, bool) {
+	return filmRecord(view.ReadTx, distributor, view.film)
+}
+
+func (view filmView) List() []string {
+	return filmDistributors(view.ReadTx, view.film)
+}
+
+func (view filmView) History(distributor string) []PermissionChange {
+	return view.ReadTx.History(journalKey(distributor, view.film))
+}
+
+func (view filmView) Versions(distributor string) []ContractVersion {
+	return view.ReadTx.Versions(journalKey(distributor, view.film))
+}
+
+func filmRecord(reader recordReader, distributor, film string) (Record, bool) {
+	record, ok := reader.Get(distributor)
+	if !ok {
+		return Record{}, false
+	}
+	if filmRecord, onFilm := record.Films[film]; onFilm {
+		return filmRecord, true
+	}
+	return Record{Permissions: permissions.New().DTO()}, true
+}
+
+func filmDistributors(lister recordLister, film string) []string {
+	distributors := []string{}
+	for _, distributor := range lister.List() {
+		if record, ok := lister.Get(distributor); ok {
+			if _, onFilm := record.Films[film]; onFilm {
+				distributors = append(distributors, distributor)
+			}
+		}
+	}
+	return distributors
+}
+
+// scopeTx returns the view of the transaction on the rights for the film, or tx itself for the rights on the whole catalog (film "").
+// Writes to a film are recorded in its own permission history. The film is stored as a key of the records, so it's copied:
+// it may come from a request, whose strings are only valid while it's handled.
+func scopeTx(tx Tx, film string) (Tx, error) {
+	if film == "" {
+		return tx, nil
+	}
+	if _, exists := tx.GetFilm(film); !exists {
+		return nil, abort(response.CreateError(404, FILM_NOT_FOUND, fmt.Errorf("film %s not found", film)))
+	}
+	return &normalizingTx{Tx: &historyTx{Tx: &filmTx{Tx: tx, film: strings.Clone(film)}, now: time.Now().UTC()}}, nil
+}
+
+// scopeView is the read-only counterpart of scopeTx. It returns false if the film doesn't exist.
+func scopeView(view ReadTx, film string) (ReadTx, bool) {
+	if film == "" {
+		return view, true
+	}
+	if _, exists := view.GetFilm(film); !exists {
+		return nil, false
+	}
+	return filmView{ReadTx: view, film: film}, true
+}
+
+// filmNotFoundResponse is the response to a request on a film that isn't in the catalog.
+func filmNotFoundResponse(film string) response.Response {
+	return response.CreateError(404, FILM_NOT_FOUND, fmt.Errorf("film %s not found", film))
+}
+
+func (db *DataBank) AddFilm(id, title string) response.Response {
+	err := db.update(func(tx Tx) error {
+		if _, exists := tx.GetFilm(id); exists {
+			return abort(response.CreateError(400, "FILM_EXISTS", fmt.Errorf("film %s already exists", id)))
+		}
+		tx.PutFilm(id, Film{Title: title})
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+	return createdResponse
+}
+
+func (db *DataBank) GetFilms() response.Response {
+	films := []dto.FilmData{}
+	db.store.View(func(view ReadTx) {
+		ids := view.ListFilms()
+		sort.Strings(ids)
+		for _, id := range ids {
+			film, _ := view.GetFilm(id)
+			films = append(films, dto.FilmData{ID: id, Title: film.Title, Distributors: filmDistributorsSorted(view, id)})
+		}
+	})
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"films": films,
+	})
+}
+
+func (db *DataBank) GetFilm(id string) response.Response {
+	var (
+		data   dto.FilmData
+		exists bool
+	)
+	db.store.View(func(view ReadTx) {
+		var film Film
+		if film, exists = view.GetFilm(id); exists {
+			data = dto.FilmData{ID: id, Title: film.Title, Distributors: filmDistributorsSorted(view, id)}
+		}
+	})
+	if !exists {
+		return filmNotFoundResponse(id)
+	}
+	return response.CreateSuccess(200, "SUCCESS", data)
+}
+
+func (db *DataBank) UpdateFilm(id, title string) response.Response {
+	err := db.update(func(tx Tx) error {
+		if _, exists := tx.GetFilm(id); !exists {
+			return abort(filmNotFoundResponse(id))
+		}
+		tx.PutFilm(id, Film{Title: title})
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+	return successResponse
+}
+
+// RemoveFilm removes the film from the catalog, along with every right distributors have on it.
+func (db *DataBank) RemoveFilm(id string) response.Response {
+	var revoked []string
+	err := db.update(func(tx Tx) error {
+		if _, exists := tx.GetFilm(id); !exists {
+			return abort(filmNotFoundResponse(id))
+		}
+		revoked = filmDistributors(tx, id)
+		sort.Strings(revoked)
+		scoped := &filmTx{Tx: tx, film: id}
+		for _, distributor := range revoked {
+			scoped.Delete(distributor)
+		}
+		tx.DeleteFilm(id)
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"revoked": revoked,
+	})
+}
+
+func filmDistributorsSorted(view ReadTx, film string) []string {
+	distributors := filmDistributors(view, film)
+	sort.Strings(distributors)
+	return distributors
+}
+
+// filmScopes returns the scopes of rights in the store: the whole catalog (""), followed by every film, sorted by id.
+func filmScopes(tx Tx) []string {
+	films := tx.ListFilms()
+	sort.Strings(films)
+	return append([]string{""}, films...)
+}
@@ -0,0 +1,138 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/response"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	HIERARCHY_MISMATCH = "HIERARCHY_MISMATCH"
+)
+
+// recordLister is satisfied by both Store and Tx
+type recordLister interface {
+	recordReader
+	List() []string
+}
+
+// getLineage returns the distributor followed by its ancestors, up to its top-level distributor.
+func getLineage(reader recordReader, distributor string) ([]string, bool) {
+	record, ok := reader.Get(distributor)
+	if !ok {
+		return nil, false
+	}
+
+	lineage := []string{distributor}
+	visited := map[string]bool{distributor: true}
+	for record.Parent != "" && !visited[record.Parent] { //visited: guard against a corrupted (cyclic) store
+		lineage = append(lineage, record.Parent)
+		visited[record.Parent] = true
+		if record, ok = reader.Get(record.Parent); !ok {
+			break
+		}
+	}
+	return lineage, true
+}
+
+// getChildren returns the distributors whose parent is the given distributor, sorted by name.
+func getChildren(lister recordLister, distributor string) []string {
+	children := []string{}
+	for _, candidate := range lister.List() {
+		if record, ok := lister.Get(candidate); ok && record.Parent == distributor {
+			children = append(children, candidate)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// getDescendants returns every descendant of the distributor (children before their own children), not including itself.
+func getDescendants(lister recordLister, distributor string) []string {
+	descendants := []string{}
+	for _, child := range getChildren(lister, distributor) {
+		descendants = append(descendants, child)
+		descendants = append(descendants, getDescendants(lister, child)...)
+	}
+	return descendants
+}
+
+// validateContractLineage checks the distributor chain in the contract heading against the stored hierarchy:
+// every ancestor in the chain must exist and be the parent of the one before it,
+// and the recipient must not already be placed elsewhere in the hierarchy.
+func validateContractLineage(reader recordReader, contract dto.Contract) error {
+	lineage := contract.Lineage
+	if len(lineage) == 0 {
+		lineage = []string{contract.ContractRecipient}
+		if contract.ParentDistributor != nil {
+			lineage = append(lineage, *contract.ParentDistributor)
+		}
+	}
+
+	for i := 1; i < len(lineage); i++ {
+		if _, exists := reader.Get(lineage[i]); !exists {
+			if i == 1 {
+				return abort(response.CreateError(404, "PARENT_DISTRIBUTOR_NOT_FOUND", fmt.Errorf("parent distributor %s not found", lineage[i])))
+			}
+			return abort(response.CreateError(404, "PARENT_DISTRIBUTOR_NOT_FOUND", fmt.Errorf("distributor %s in the heading not found", lineage[i])))
+		}
+	}
+	for i := 1; i+1 < len(lineage); i++ {
+		if record, _ := reader.Get(lineage[i]); record.Parent != lineage[i+1] {
+			return abort(response.CreateError(409, HIERARCHY_MISMATCH, fmt.Errorf("heading says %s < %s, but %s", lineage[i], lineage[i+1], describeParent(lineage[i], record.Parent))))
+		}
+	}
+
+	recipient := lineage[0]
+	parent := ""
+	if len(lineage) > 1 {
+		parent = lineage[1]
+	}
+
+	if record, exists := reader.Get(recipient); exists && record.Parent != "" && record.Parent != parent {
+		if parent == "" {
+			return abort(response.CreateError(409, HIERARCHY_MISMATCH, fmt.Errorf("%s, it cannot receive a top-level contract", describeParent(recipient, record.Parent))))
+		}
+		return abort(response.CreateError(409, HIERARCHY_MISMATCH, fmt.Errorf("%s, it cannot receive a contract as a sub-distributor of %s", describeParent(recipient, record.Parent), parent)))
+	}
+
+	if parent != "" {
+		parentLineage, _ := getLineage(reader, parent)
+		for _, ancestor := range parentLineage {
+			if ancestor == recipient {
+				return abort(response.CreateError(409, HIERARCHY_MISMATCH, fmt.Errorf("%s is an ancestor of %s (%s), it cannot be its sub-distributor", recipient, parent, strings.Join(parentLineage, " < "))))
+			}
+		}
+	}
+	return nil
+}
+
+func describeParent(distributor, parent string) string {
+	if parent == "" {
+		return distributor + " is a top-level distributor"
+	}
+	return distributor + " is a sub-distributor of " + parent
+}
+
+func (db *DataBank) GetLineage(distributor string) response.Response {
+	lineage, ok := getLineage(db.store, distributor)
+	if !ok {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"distributor": distributor,
+		"lineage":     lineage,
+	})
+}
+
+func (db *DataBank) GetChildren(distributor string) response.Response {
+	if !db.distributorExists(distributor) {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"distributor": distributor,
+		"children":    getChildren(db.store, distributor),
+	})
+}
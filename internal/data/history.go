@@ -0,0 +1,159 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"slices"
+	"time"
+)
+
+/*
+Permission history.
+
+Every write that changes what a distributor holds (its parent, permissions, or time-bound grants) appends the resulting state
+to the distributor's history journal, stamped with the time of the transaction. The state of the whole DataBank at any past instant
+is then the last entry of each history at or before that instant, which asOfReader serves to the same code that reads the current state.
+
+Removing a distributor appends a tombstone to its history, which is kept: it held what it held until then, and nothing after.
+Records written before the history was kept have none, and are read as they are now.
+*/
+
+// PermissionChange is the state of a distributor's permissions after a write, from At until the next change.
+type PermissionChange struct {
+	At          time.Time        `json:"at"`
+	Parent      string           `json:"parent,omitempty"`
+	Permissions dto.Permissions  `json:"permissions"`
+	Base        *dto.Permissions `json:"base,omitempty"`
+	Grants      []Grant          `json:"grants,omitempty"`
+	Removed     bool             `json:"removed,omitempty"` //the distributor was removed, the other fields are empty
+}
+
+// historyTx records the permission changes made through the transaction in the history of the distributors.
+type historyTx struct {
+	Tx
+	now time.Time
+}
+
+func (tx *historyTx) Put(distributor string, record Record) {
+	if old, exists := tx.Tx.Get(distributor); !exists || !samePermissionState(old, record) {
+		tx.Tx.AppendHistory(distributor, PermissionChange{
+			At:          tx.now,
+			Parent:      record.Parent,
+			Permissions: record.Permissions,
+			Base:        record.Base,
+			Grants:      record.Grants,
+		})
+	}
+	tx.Tx.Put(distributor, record)
+}
+
+// Delete ends the history of the distributor with a tombstone, and so the histories of its rights on films, which go along with it.
+func (tx *historyTx) Delete(distributor string) {
+	if record, exists := tx.Tx.Get(distributor); exists {
+		tx.tombstone(distributor)
+		for film := range record.Films {
+			tx.tombstone(journalKey(distributor, film))
+		}
+	}
+	tx.Tx.Delete(distributor)
+}
+
+func (tx *historyTx) tombstone(key string) {
+	if history := tx.Tx.History(key); len(history) > 0 && !history[len(history)-1].Removed {
+		tx.Tx.AppendHistory(key, PermissionChange{At: tx.now, Removed: true})
+	}
+}
+
+func samePermissionState(a, b Record) bool {
+	if a.Parent != b.Parent || len(a.Grants) != len(b.Grants) || (a.Base == nil) != (b.Base == nil) {
+		return false
+	}
+	if !permissions.FromDTO(a.Permissions).Equal(permissions.FromDTO(b.Permissions)) {
+		return false
+	}
+	if a.Base != nil && !permissions.FromDTO(*a.Base).Equal(permissions.FromDTO(*b.Base)) {
+		return false
+	}
+	for i := range a.Grants {
+		ga, gb := a.Grants[i], b.Grants[i]
+		if ga.Version != gb.Version || !sameTime(ga.ValidFrom, gb.ValidFrom) || !sameTime(ga.ValidUntil, gb.ValidUntil) ||
+			!permissions.FromDTO(ga.Permissions).Equal(permissions.FromDTO(gb.Permissions)) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// stateAt returns the record as it was at t according to its history, and false if it didn't exist then.
+func stateAt(history []PermissionChange, t time.Time) (Record, bool) {
+	//the last change at or before t
+	i, found := slices.BinarySearchFunc(history, t, func(change PermissionChange, t time.Time) int {
+		return change.At.Compare(t)
+	})
+	if found {
+		for i+1 < len(history) && history[i+1].At.Equal(t) {
+			i++
+		}
+	} else {
+		i--
+	}
+	if i < 0 || history[i].Removed {
+		return Record{}, false
+	}
+
+	change := history[i]
+	return Record{
+		Parent:      change.Parent,
+		Permissions: change.Permissions,
+		Base:        change.Base,
+		Grants:      change.Grants,
+	}, true
+}
+
+// historyReader is satisfied by both ReadTx and Tx
+type historyReader interface {
+	recordReader
+	History(distributor string) []PermissionChange
+}
+
+// asOfReader reads the records as they were at a past instant.
+type asOfReader struct {
+	reader historyReader
+	at     time.Time
+}
+
+func (r asOfReader) Get(distributor string) (Record, bool) {
+	history := r.reader.History(distributor)
+	if len(history) == 0 {
+		return r.reader.Get(distributor) //written before the history was kept
+	}
+	return stateAt(history, r.at)
+}
+
+// permissionsAsOf returns the permissions the distributor held at asOf, or holds now if asOf is nil, along with its lineage at that time.
+// The permissions must be treated as read-only.
+func permissionsAsOf(reader historyReader, distributor string, asOf *time.Time) (permissions.PermissionSet, []string, bool) {
+	if asOf == nil {
+		record, ok := reader.Get(distributor)
+		if !ok {
+			return permissions.PermissionSet{}, nil, false
+		}
+		lineage, _ := getLineage(reader, distributor)
+		return permissions.FromDTO(record.Permissions), lineage, true
+	}
+
+	past := asOfReader{reader: reader, at: *asOf}
+	held, ok := permissionsAt(past, distributor, *asOf)
+	if !ok {
+		return permissions.PermissionSet{}, nil, false
+	}
+	lineage, _ := getLineage(past, distributor)
+	return held, lineage, true
+}
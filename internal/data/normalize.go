@@ -0,0 +1,87 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/response"
+	"fmt"
+	"slices"
+)
+
+// normalizingTx stores every record it writes with its rules in their minimal form (see permissions.PermissionSet.Normalize),
+// so that including every city of a province one by one is stored as the province, and so on.
+type normalizingTx struct {
+	Tx
+}
+
+func (tx *normalizingTx) Put(distributor string, record Record) {
+	tx.Tx.Put(distributor, normalizeRecord(record))
+}
+
+// normalizeRecord returns the record with its permissions, base and grants normalized.
+func normalizeRecord(record Record) Record {
+	record.Permissions = permissions.FromDTO(record.Permissions).Normalize().DTO()
+	if record.Base != nil {
+		base := permissions.FromDTO(*record.Base).Normalize().DTO()
+		record.Base = &base
+	}
+	if len(record.Grants) > 0 {
+		grants := make([]Grant, len(record.Grants))
+		for i, grant := range record.Grants {
+			grant.Permissions = permissions.FromDTO(grant.Permissions).Normalize().DTO()
+			grants[i] = grant
+		}
+		record.Grants = grants
+	}
+	return record
+}
+
+// sameRules tells whether the records have the very same rules, and not only the same coverage.
+func sameRules(a, b Record) bool {
+	if !sameLines(a.Permissions, b.Permissions) || (a.Base == nil) != (b.Base == nil) || len(a.Grants) != len(b.Grants) {
+		return false
+	}
+	if a.Base != nil && !sameLines(*a.Base, *b.Base) {
+		return false
+	}
+	for i := range a.Grants {
+		if !sameLines(a.Grants[i].Permissions, b.Grants[i].Permissions) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameLines(a, b dto.Permissions) bool {
+	includedA, excludedA := permissions.FromDTO(a).RegionLists()
+	includedB, excludedB := permissions.FromDTO(b).RegionLists()
+	return slices.Equal(includedA, includedB) && slices.Equal(excludedA, excludedB)
+}
+
+// NormalizePermissions rewrites the rules of the distributor (on the film, if given) into their minimal equivalent form.
+// Every change is normalized as it's written, so this only rewrites the rules stored before normalization was introduced.
+func (db *DataBank) NormalizePermissions(distributor, film string) response.Response {
+	var data dto.NormalizePermissionsData
+	err := db.update(func(tx Tx) error {
+		scoped, err := scopeTx(tx, film)
+		if err != nil {
+			return err
+		}
+		record, ok := scoped.Get(distributor)
+		if !ok {
+			return abort(response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor)))
+		}
+
+		normalized := normalizeRecord(record)
+		if data.Changed = !sameRules(record, normalized); data.Changed {
+			scoped.Put(distributor, normalized)
+		}
+		data.Distributor = distributor
+		data.Included, data.Excluded = permissions.FromDTO(normalized.Permissions).RegionLists()
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+	return response.CreateSuccess(200, "SUCCESS", data)
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// ContractStrict rejects the contracts of sub-distributors that include regions their parent doesn't hold,
+	// and the changes conflicting with exclusive rights.
+	ContractStrict = "strict"
+	// ContractLenient trims such regions off the contract, and reports them as warnings. Exclusivity conflicts are reported, not rejected.
+	ContractLenient = "lenient"
+
+	EXCEEDS_PARENT_PERMISSIONS = "EXCEEDS_PARENT_PERMISSIONS"
+)
+
+// parentViolations returns the INCLUDE lines of the contract for regions the parent doesn't hold.
+// A region the parent holds isn't a violation even if the parent excludes parts of it: the sub-distributor inherits those exclusions.
+// The parent holds what it holds or will hold as of now, time-bound contracts included.
+func parentViolations(reader recordReader, contract dto.Contract, now time.Time) []dto.ContractViolation {
+	violations := []dto.ContractViolation{}
+	if contract.ParentDistributor == nil {
+		return violations
+	}
+	parent := *contract.ParentDistributor
+
+	parentPermissions, ok := getHeldPermissions(reader, parent, now)
+	if !ok {
+		return violations
+	}
+
+	lines, _ := permissions.FromDTO(contract.Permissions).RegionLists()
+
+	for _, line := range lines {
+		region, err := regions.GetRegionDetails(line)
+		if err != nil || parentPermissions.Covers(region.CountryCode, region.ProvinceCode, region.CityCode) {
+			continue
+		}
+
+		violation := dto.ContractViolation{
+			Line:   "INCLUDE: " + line,
+			Reason: parent + " doesn't hold " + line,
+		}
+		//the nearest rule decides the region, so if there is one, it's an EXCLUDE
+		if rules := parentPermissions.MatchingRules(region); len(rules) > 0 {
+			violation.BlockedBy = &rules[len(rules)-1]
+			violation.Reason += ", it has " + violation.BlockedBy.Rule
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
+
+// placementLosses returns the regions the recipient holds that the parent doesn't, which it would lose once placed under the parent,
+// as INCLUDE lines of what it holds.
+func placementLosses(reader recordReader, recipient, parent string, now time.Time) []dto.ContractViolation {
+	losses := []dto.ContractViolation{}
+	held, exists := getHeldPermissions(reader, recipient, now)
+	parentPermissions, ok := getHeldPermissions(reader, parent, now)
+	if !exists || !ok {
+		return losses
+	}
+
+	lines, _ := held.Subtract(parentPermissions).Normalize().RegionLists()
+	for _, line := range lines {
+		loss := dto.ContractViolation{
+			Line:   "INCLUDE: " + line,
+			Reason: recipient + " holds " + line + ", which " + parent + " doesn't hold",
+		}
+		if region, err := regions.GetRegionDetails(line); err == nil {
+			if rules := parentPermissions.MatchingRules(region); len(rules) > 0 {
+				loss.BlockedBy = &rules[len(rules)-1]
+				loss.Reason += ", it has " + loss.BlockedBy.Rule
+			}
+		}
+		losses = append(losses, loss)
+	}
+	return losses
+}
+
+// violatingLines lists the lines of the violations, for error messages.
+func violatingLines(violations []dto.ContractViolation) string {
+	lines := make([]string, len(violations))
+	for i, violation := range violations {
+		lines[i] = violation.Line
+	}
+	return strings.Join(lines, ", ")
+}
+
+// exceedsParentError rejects a change (a "contract", an "inclusion", a "placement") including regions the parent doesn't hold, listing the violations.
+func exceedsParentError(change, parent string, violations []dto.ContractViolation) error {
+	resp := response.CreateError(409, EXCEEDS_PARENT_PERMISSIONS, fmt.Errorf("%s includes regions %s doesn't hold: %s", change, parent, violatingLines(violations)))
+	resp.Data = map[string]interface{}{
+		"violations": violations,
+	}
+	return abort(resp)
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"sort"
+	"sync"
+)
+
+type (
+	// regionIndex is an inverted index of the distributors by the countries they hold rights in.
+	// A distributor can cover a region only if it marks some region of the same country,
+	// so the index narrows a reverse lookup down to the few distributors worth checking.
+	regionIndex struct {
+		mu        sync.RWMutex
+		byCountry map[string]map[string]bool //country -> distributors
+		countries map[string]map[string]bool //distributor -> countries, to unindex it
+	}
+
+	// indexingTx records which distributors a transaction writes, to re-index them once it's committed.
+	indexingTx struct {
+		Tx
+		written map[string]bool
+	}
+)
+
+func newRegionIndex(store Store) *regionIndex {
+	index := &regionIndex{
+		byCountry: make(map[string]map[string]bool),
+		countries: make(map[string]map[string]bool),
+	}
+	for _, distributor := range store.List() {
+		index.refresh(store, distributor)
+	}
+	return index
+}
+
+// refresh re-indexes the distributor as it currently is in the store. The caller holds the index lock.
+func (index *regionIndex) refresh(store recordReader, distributor string) {
+	for country := range index.countries[distributor] {
+		delete(index.byCountry[country], distributor)
+		if len(index.byCountry[country]) == 0 {
+			delete(index.byCountry, country)
+		}
+	}
+	delete(index.countries, distributor)
+
+	record, ok := store.Get(distributor)
+	if !ok {
+		return
+	}
+
+	marked := make(map[string]map[string]map[string]bool)
+	permissions.FromDTO(record.Permissions).MarkedRegions(marked)
+	if len(marked) == 0 {
+		return
+	}
+
+	index.countries[distributor] = make(map[string]bool, len(marked))
+	for country := range marked {
+		index.countries[distributor][country] = true
+		if index.byCountry[country] == nil {
+			index.byCountry[country] = make(map[string]bool)
+		}
+		index.byCountry[country][distributor] = true
+	}
+}
+
+// candidates returns the distributors that mark some region of the country.
+// The caller holds the index lock, at least for reading.
+func (index *regionIndex) candidates(country string) []string {
+	distributors := make([]string, 0, len(index.byCountry[country]))
+	for distributor := range index.byCountry[country] {
+		distributors = append(distributors, distributor)
+	}
+	return distributors
+}
+
+func (tx *indexingTx) Put(distributor string, record Record) {
+	tx.written[distributor] = true
+	tx.Tx.Put(distributor, record)
+}
+
+func (tx *indexingTx) Delete(distributor string) {
+	tx.written[distributor] = true
+	tx.Tx.Delete(distributor)
+}
+
+// GetRegionDistributors returns every distributor that holds rights in the region, fully or partially, sorted by name.
+func (db *DataBank) GetRegionDistributors(regionString string) response.Response {
+	region, err := regions.GetRegionDetails(regionString)
+	if err != nil {
+		return response.CreateError(404, REGION_NOT_FOUND, err)
+	}
+
+	//update holds the index lock until the index reflects its commit, so the store read under it is as the index has it
+	db.index.mu.RLock()
+	defer db.index.mu.RUnlock()
+
+	distributors := []map[string]string{}
+	db.store.View(func(view ReadTx) {
+		candidates := db.index.candidates(region.CountryCode)
+		sort.Strings(candidates)
+
+		for _, distributor := range candidates {
+			if _, status := isAllowed(view, distributor, region); status == FULLY_ALLOWED || status == PARTIALLY_ALLOWED {
+				distributors = append(distributors, map[string]string{
+					"distributor": distributor,
+					"status":      status,
+				})
+			}
+		}
+	})
+
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"region":       regionString,
+		"distributors": distributors,
+	})
+}
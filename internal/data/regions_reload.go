@@ -0,0 +1,142 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"challenge16/internal/response"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	INVALID_REGION_CATALOG = "INVALID_REGION_CATALOG"
+)
+
+/*
+Region catalog reloads.
+
+The region catalog (cities.csv) can be reloaded while the server runs. The new file is loaded into a fresh catalog and validated,
+and the catalog is swapped in at once: region lookups never wait on a reload, and go on with the previous catalog until the swap.
+A file that fails validation leaves the current catalog in place.
+
+Rules referencing regions the new catalog lacks are kept as they are, and reported. Normalization leaves them alone too,
+so they outlive later changes to the distributor's rules, and take effect again if a later catalog brings the regions back.
+*/
+
+// ReloadRegions loads the region catalog again from the file it was loaded from, and swaps it in, reporting the regions
+// that distributors' rules reference but that vanished from the file.
+func (db *DataBank) ReloadRegions() response.Response {
+	source := regions.Source()
+	catalog, err := regions.LoadCatalog(source)
+	if err != nil {
+		return response.CreateError(400, INVALID_REGION_CATALOG, err)
+	}
+
+	var vanished []dto.VanishedRegion
+	//in a transaction, so that no rule is written against the previous catalog while the new one is checked and swapped in
+	err = db.store.Update(func(tx Tx) error {
+		vanished = vanishedRegions(tx, catalog)
+		regions.Install(catalog)
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+
+	countries, provinces, cities := catalog.Counts()
+	return response.CreateSuccess(200, "SUCCESS", dto.RegionReloadData{
+		Source:    source,
+		Countries: countries,
+		Provinces: provinces,
+		Cities:    cities,
+		Vanished:  vanished,
+	})
+}
+
+// vanishedRegions returns the regions of every distributor's rules, on the whole catalog and on films, that the catalog doesn't have,
+// sorted by distributor and film.
+func vanishedRegions(lister recordLister, catalog *regions.Catalog) []dto.VanishedRegion {
+	vanished := []dto.VanishedRegion{}
+	for _, distributor := range sortedDistributors(lister) {
+		record, _ := lister.Get(distributor)
+		vanished = append(vanished, vanishedFromRecord(distributor, "", record, catalog)...)
+
+		films := make([]string, 0, len(record.Films))
+		for film := range record.Films {
+			films = append(films, film)
+		}
+		sort.Strings(films)
+		for _, film := range films {
+			vanished = append(vanished, vanishedFromRecord(distributor, film, record.Films[film], catalog)...)
+		}
+	}
+	return vanished
+}
+
+// vanishedFromRecord returns the regions of the rules of the record that the catalog doesn't have, the time-bound ones included.
+func vanishedFromRecord(distributor, film string, record Record, catalog *regions.Catalog) []dto.VanishedRegion {
+	rules := []permissions.PermissionSet{permissions.FromDTO(record.Permissions)}
+	if record.Base != nil {
+		rules = append(rules, permissions.FromDTO(*record.Base))
+	}
+	for _, grant := range record.Grants {
+		rules = append(rules, permissions.FromDTO(grant.Permissions))
+	}
+
+	vanished := []dto.VanishedRegion{}
+	seen := make(map[dto.VanishedRegion]bool)
+	report := func(rule string, lines []string) {
+		for _, region := range lines {
+			entry := dto.VanishedRegion{Distributor: distributor, Film: film, Region: region, Rule: rule}
+			if !seen[entry] && !catalog.HasRegion(region) {
+				seen[entry] = true
+				vanished = append(vanished, entry)
+			}
+		}
+	}
+	for _, set := range rules {
+		included, excluded := set.RegionLists()
+		report("INCLUDE", included)
+		report("EXCLUDE", excluded)
+	}
+	return vanished
+}
+
+// StartRegionWatcher checks every interval whether the file of the region catalog changed, and reloads it if so, until stop is called.
+func (db *DataBank) StartRegionWatcher(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	last, _ := os.Stat(regions.Source())
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(regions.Source())
+				if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+					continue
+				}
+				last = info
+
+				resp := db.ReloadRegions()
+				if resp.Error != nil {
+					log.Println("error reloading regions:", resp.Error)
+				} else if data := resp.Data.(dto.RegionReloadData); len(data.Vanished) > 0 {
+					log.Println("regions reloaded, rules reference regions that vanished:", data.Vanished)
+				} else {
+					log.Println("regions reloaded from", data.Source)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
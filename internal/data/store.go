@@ -0,0 +1,338 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+const (
+	MemoryStore = "memory"
+	FileStore   = "file"
+)
+
+type (
+	// Record is the current state of a distributor. Its contract versions and permission history are kept apart, in the journals.
+	Record struct {
+		Parent      string          `json:"parent,omitempty"` //empty for top-level distributors
+		Permissions dto.Permissions `json:"permissions"`
+		Exclusive   bool            `json:"exclusive,omitempty"` //holds its rights exclusively (see exclusivity.go)
+
+		// Base and Grants are set only for distributors with time-bound permissions (see validity.go),
+		// in which case Permissions is what they hold as of the last time it was computed.
+		Base   *dto.Permissions `json:"base,omitempty"`
+		Grants []Grant          `json:"grants,omitempty"`
+
+		Films map[string]Record `json:"films,omitempty"` //the rights of the distributor on specific films, by film id (see films.go)
+	}
+
+	// journals are the logs kept next to the records, by distributor (or by distributor and film, see journalKey), oldest entry first.
+	// Entries are only ever appended to them, so that a transaction is stored as the entries it added rather than as whole logs.
+	// They outlive the records: a distributor that is removed keeps its history and its versions.
+	journals struct {
+		History  map[string][]PermissionChange `json:"history,omitempty"`  //the state after every change of the permissions (see history.go)
+		Versions map[string][]ContractVersion  `json:"versions,omitempty"` //every contract applied (see versions.go)
+	}
+
+	// Film is a title of the catalog, that contracts can be scoped to.
+	Film struct {
+		Title string `json:"title"`
+	}
+
+	// Store keeps the record of every distributor.
+	// Implementations must be safe for concurrent use. Records returned by a Store share their permission maps, and must be treated as read-only.
+	Store interface {
+		Get(distributor string) (Record, bool)
+		List() []string
+		Put(distributor string, record Record) error
+		Delete(distributor string) error
+
+		// Update runs fn in a transaction, serialized with every other Update.
+		// The writes made through tx are applied all at once if fn returns nil, and discarded if it returns an error (which Update returns).
+		Update(fn func(tx Tx) error) error
+
+		// View runs fn with a consistent read-only view of the store: no transaction is committed while it runs.
+		View(fn func(view ReadTx))
+
+		Close() error
+	}
+
+	// ReadTx is the view of the Store inside View.
+	ReadTx interface {
+		Get(distributor string) (Record, bool)
+		List() []string
+
+		GetFilm(id string) (Film, bool)
+		ListFilms() []string
+
+		History(distributor string) []PermissionChange
+		Versions(distributor string) []ContractVersion
+	}
+
+	// Tx is the view of the Store inside a transaction. It sees its own writes.
+	Tx interface {
+		Get(distributor string) (Record, bool)
+		List() []string
+		Put(distributor string, record Record)
+		Delete(distributor string)
+
+		GetFilm(id string) (Film, bool)
+		ListFilms() []string
+		PutFilm(id string, film Film)
+		DeleteFilm(id string)
+
+		// History and Versions return the journals of the distributor, which must be treated as read-only.
+		// A change appended at the same time as the last one appended in the transaction replaces it.
+		History(distributor string) []PermissionChange
+		AppendHistory(distributor string, change PermissionChange)
+		Versions(distributor string) []ContractVersion
+		AppendVersion(distributor string, version ContractVersion)
+	}
+
+	// recordReader is satisfied by both Store and Tx
+	recordReader interface {
+		Get(distributor string) (Record, bool)
+	}
+
+	memoryStore struct {
+		mu           sync.RWMutex
+		distributors map[string]Record
+		films        map[string]Film
+		journals     journals
+	}
+
+	transaction struct {
+		base    map[string]Record
+		changes map[string]*Record //nil value => deleted
+
+		baseFilms   map[string]Film
+		filmChanges map[string]*Film //nil value => deleted
+
+		baseJournals journals
+		appended     journals //entries appended by the transaction
+	}
+)
+
+// OpenStore opens the store of the given backend (MemoryStore or FileStore).
+// dir and snapshotInterval are used only by the file backend.
+func OpenStore(backend, dir string, snapshotInterval int) (Store, error) {
+	switch backend {
+	case MemoryStore:
+		return NewMemoryStore(), nil
+	case FileStore:
+		return OpenFileStore(dir, snapshotInterval)
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", backend)
+	}
+}
+
+// NewMemoryStore creates a Store that keeps everything in memory. Its data is lost when the process exits.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		distributors: make(map[string]Record),
+		films:        make(map[string]Film),
+		journals:     newJournals(),
+	}
+}
+
+func (s *memoryStore) Get(distributor string) (Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.distributors[distributor]
+	return record, ok
+}
+
+func (s *memoryStore) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	distributors := make([]string, 0, len(s.distributors))
+	for distributor := range s.distributors {
+		distributors = append(distributors, distributor)
+	}
+	return distributors
+}
+
+func (s *memoryStore) Put(distributor string, record Record) error {
+	return s.Update(func(tx Tx) error {
+		tx.Put(distributor, record)
+		return nil
+	})
+}
+
+func (s *memoryStore) Delete(distributor string) error {
+	return s.Update(func(tx Tx) error {
+		tx.Delete(distributor)
+		return nil
+	})
+}
+
+func (s *memoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := newTransaction(s.distributors, s.films, s.journals)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	tx.applyTo(s.distributors, s.films, s.journals)
+	return nil
+}
+
+func (s *memoryStore) View(fn func(view ReadTx)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(newTransaction(s.distributors, s.films, s.journals))
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+func newJournals() journals {
+	return journals{
+		History:  make(map[string][]PermissionChange),
+		Versions: make(map[string][]ContractVersion),
+	}
+}
+
+func newTransaction(base map[string]Record, baseFilms map[string]Film, baseJournals journals) *transaction {
+	return &transaction{
+		base:         base,
+		changes:      make(map[string]*Record),
+		baseFilms:    baseFilms,
+		filmChanges:  make(map[string]*Film),
+		baseJournals: baseJournals,
+		appended:     newJournals(),
+	}
+}
+
+func (tx *transaction) Get(distributor string) (Record, bool) {
+	if record, changed := tx.changes[distributor]; changed {
+		if record == nil {
+			return Record{}, false
+		}
+		return *record, true
+	}
+	record, ok := tx.base[distributor]
+	return record, ok
+}
+
+func (tx *transaction) List() []string {
+	distributors := make([]string, 0, len(tx.base)+len(tx.changes))
+	for distributor := range tx.base {
+		if record, changed := tx.changes[distributor]; !changed || record != nil {
+			distributors = append(distributors, distributor)
+		}
+	}
+	for distributor, record := range tx.changes {
+		if _, inBase := tx.base[distributor]; !inBase && record != nil {
+			distributors = append(distributors, distributor)
+		}
+	}
+	return distributors
+}
+
+func (tx *transaction) Put(distributor string, record Record) {
+	tx.changes[distributor] = &record
+}
+
+func (tx *transaction) Delete(distributor string) {
+	tx.changes[distributor] = nil
+}
+
+func (tx *transaction) GetFilm(id string) (Film, bool) {
+	if film, changed := tx.filmChanges[id]; changed {
+		if film == nil {
+			return Film{}, false
+		}
+		return *film, true
+	}
+	film, ok := tx.baseFilms[id]
+	return film, ok
+}
+
+func (tx *transaction) ListFilms() []string {
+	ids := make([]string, 0, len(tx.baseFilms)+len(tx.filmChanges))
+	for id := range tx.baseFilms {
+		if film, changed := tx.filmChanges[id]; !changed || film != nil {
+			ids = append(ids, id)
+		}
+	}
+	for id, film := range tx.filmChanges {
+		if _, inBase := tx.baseFilms[id]; !inBase && film != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (tx *transaction) PutFilm(id string, film Film) {
+	tx.filmChanges[id] = &film
+}
+
+func (tx *transaction) DeleteFilm(id string) {
+	tx.filmChanges[id] = nil
+}
+
+func (tx *transaction) History(distributor string) []PermissionChange {
+	return joined(tx.baseJournals.History[distributor], tx.appended.History[distributor])
+}
+
+func (tx *transaction) AppendHistory(distributor string, change PermissionChange) {
+	appended := tx.appended.History[distributor]
+	if n := len(appended); n > 0 && appended[n-1].At.Equal(change.At) {
+		appended = appended[:n-1] //changed again in the same transaction
+	}
+	tx.appended.History[distributor] = append(appended, change)
+}
+
+func (tx *transaction) Versions(distributor string) []ContractVersion {
+	return joined(tx.baseJournals.Versions[distributor], tx.appended.Versions[distributor])
+}
+
+func (tx *transaction) AppendVersion(distributor string, version ContractVersion) {
+	tx.appended.Versions[distributor] = append(tx.appended.Versions[distributor], version)
+}
+
+// joined returns the entries of a journal followed by the ones appended to it. The result never shares spare capacity with the journal,
+// which the store appends to in place.
+func joined[T any](base, appended []T) []T {
+	return append(slices.Clip(base), appended...)
+}
+
+func (tx *transaction) empty() bool {
+	return len(tx.changes) == 0 && len(tx.filmChanges) == 0 && tx.appended.empty()
+}
+
+func (j journals) empty() bool {
+	return len(j.History) == 0 && len(j.Versions) == 0
+}
+
+// appendTo appends the entries of j to the journals.
+func (j journals) appendTo(journals journals) {
+	for distributor, changes := range j.History {
+		journals.History[distributor] = append(journals.History[distributor], changes...)
+	}
+	for distributor, versions := range j.Versions {
+		journals.Versions[distributor] = append(journals.Versions[distributor], versions...)
+	}
+}
+
+func (tx *transaction) applyTo(distributors map[string]Record, films map[string]Film, journals journals) {
+	tx.appended.appendTo(journals)
+	for distributor, record := range tx.changes {
+		if record == nil {
+			delete(distributors, distributor)
+		} else {
+			distributors[distributor] = *record
+		}
+	}
+	for id, film := range tx.filmChanges {
+		if film == nil {
+			delete(films, id)
+		} else {
+			films[id] = *film
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"log"
+	"slices"
+	"sort"
+	"time"
+)
+
+/*
+Time-bound contracts.
+
+A contract with VALID FROM/UNTIL lines doesn't merge into the permissions of its recipient: it's kept as a Grant of the record,
+next to the untimed permissions (Record.Base). What the distributor holds at a time t is then
+	(base ∪ grants valid at t) ∩ what its parent holds at t
+and Record.Permissions holds it as of now, so that everything reading the permissions needs to know nothing about time.
+It's recomputed (materialize) whenever the record or its parent changes, and by the expiry scheduler when a grant starts or ends.
+
+Records that never had a time-bound contract, in a lineage that never had one either, have no Base and no Grants,
+and everything works on Record.Permissions as before.
+*/
+
+// Grant is what a time-bound contract gave its recipient.
+type Grant struct {
+	Version     int             `json:"version"` //of the contract that gave it
+	ValidFrom   *time.Time      `json:"valid_from,omitempty"`
+	ValidUntil  *time.Time      `json:"valid_until,omitempty"` //exclusive
+	Permissions dto.Permissions `json:"permissions"`
+}
+
+func (g Grant) activeAt(t time.Time) bool {
+	return (g.ValidFrom == nil || !t.Before(*g.ValidFrom)) && !g.expiredAt(t)
+}
+
+func (g Grant) expiredAt(t time.Time) bool {
+	return g.ValidUntil != nil && !t.Before(*g.ValidUntil)
+}
+
+// timed tells whether the record has time-bound permissions, so that its Permissions have to be materialized.
+func (r Record) timed() bool {
+	return r.Base != nil || len(r.Grants) > 0
+}
+
+// basePermissions returns the permissions of the record that don't depend on time.
+func (r Record) basePermissions() permissions.PermissionSet {
+	if r.Base != nil {
+		return permissions.FromDTO(*r.Base)
+	}
+	return permissions.FromDTO(r.Permissions)
+}
+
+// ownPermissionsAt returns what the record holds at t, before considering its parent.
+func (r Record) ownPermissionsAt(t time.Time) permissions.PermissionSet {
+	if !r.timed() {
+		return permissions.FromDTO(r.Permissions)
+	}
+	own := r.basePermissions()
+	for _, grant := range r.Grants {
+		if grant.activeAt(t) {
+			own = own.Union(permissions.FromDTO(grant.Permissions))
+		}
+	}
+	return own
+}
+
+// heldPermissions returns everything the record holds or will hold as of now: the permissions its sub-distributors can be given.
+func (r Record) heldPermissions(now time.Time) permissions.PermissionSet {
+	if !r.timed() {
+		return permissions.FromDTO(r.Permissions)
+	}
+	held := r.basePermissions()
+	for _, grant := range r.Grants {
+		if !grant.expiredAt(now) {
+			held = held.Union(permissions.FromDTO(grant.Permissions))
+		}
+	}
+	return held
+}
+
+// getHeldPermissions returns a copy of the permissions the distributor holds or will hold as of now.
+func getHeldPermissions(reader recordReader, distributor string, now time.Time) (permissions.PermissionSet, bool) {
+	record, ok := reader.Get(distributor)
+	if !ok {
+		return permissions.PermissionSet{}, false
+	}
+	return record.heldPermissions(now).Copy(), true
+}
+
+// materialize returns the record with its Permissions recomputed as of now, and whether they changed.
+// Expired grants are dropped, and its base and grants are trimmed down to what its parent holds, so that a parent's expiry is final for its sub-distributors.
+func materialize(reader recordReader, record Record, now time.Time) (Record, bool) {
+	base := record.basePermissions()
+	grants := make([]Grant, 0, len(record.Grants))
+	for _, grant := range record.Grants {
+		if !grant.expiredAt(now) {
+			grants = append(grants, grant)
+		}
+	}
+
+	parent, hasParent := reader.Get(record.Parent)
+	if hasParent && record.Parent != "" {
+		held := parent.heldPermissions(now)
+		base = base.Intersect(held)
+		for i := range grants {
+			grants[i].Permissions = permissions.FromDTO(grants[i].Permissions).Intersect(held).DTO()
+		}
+	}
+
+	own := base
+	for _, grant := range grants {
+		if grant.activeAt(now) {
+			own = own.Union(permissions.FromDTO(grant.Permissions))
+		}
+	}
+	if hasParent && record.Parent != "" {
+		own = own.Intersect(permissions.FromDTO(parent.Permissions))
+	}
+
+	changed := !own.Equal(permissions.FromDTO(record.Permissions)) || len(grants) != len(record.Grants)
+
+	record.Permissions = own.DTO()
+	record.Grants = grants
+	record.Base = nil
+	if len(grants) > 0 || !base.Equal(own) {
+		baseDTO := base.DTO()
+		record.Base = &baseDTO
+	}
+	return record, changed
+}
+
+// addGrant records the (parent-filtered) permissions of a time-bound contract as a grant of the record.
+func addGrant(record Record, version int, contract dto.Contract) Record {
+	if record.Base == nil {
+		base := record.Permissions
+		record.Base = &base
+	}
+	record.Grants = append(slices.Clip(record.Grants), Grant{
+		Version:     version,
+		ValidFrom:   contract.ValidFrom,
+		ValidUntil:  contract.ValidUntil,
+		Permissions: permissions.FromDTO(contract.Permissions).Copy().DTO(),
+	})
+	return record
+}
+
+// permissionsAt returns what the distributor holds at t: its own permissions at t, within the ones of its ancestors at t.
+func permissionsAt(reader recordReader, distributor string, t time.Time) (permissions.PermissionSet, bool) {
+	lineage, ok := getLineage(reader, distributor)
+	if !ok {
+		return permissions.PermissionSet{}, false
+	}
+
+	var (
+		result       permissions.PermissionSet
+		started      bool
+		lineageTimed bool
+	)
+	for i := len(lineage) - 1; i >= 0; i-- {
+		record, ok := reader.Get(lineage[i])
+		if !ok {
+			continue
+		}
+		own := record.ownPermissionsAt(t)
+		lineageTimed = lineageTimed || record.timed()
+
+		//without anything time-bound in the lineage, the permissions are the same at any time, and already within the parent's
+		if started && lineageTimed {
+			own = own.Intersect(result)
+		}
+		result, started = own, true
+	}
+	return result, true
+}
+
+// ExpireContracts brings the permissions of every distributor with time-bound contracts up to date as of now:
+// grants that ended are dropped, the ones that started are counted in, and the changes cascade to the sub-distributors.
+// It returns the distributors whose permissions changed ("<distributor> ON FILM:<id>" for the rights on a film).
+func (db *DataBank) ExpireContracts(now time.Time) ([]string, error) {
+	changed := []string{}
+	err := db.update(func(tx Tx) error {
+		for _, film := range filmScopes(tx) {
+			scoped, _ := scopeTx(tx, film)
+			for _, distributor := range expireInScope(scoped, now) {
+				if film != "" {
+					distributor += " ON FILM:" + film
+				}
+				changed = append(changed, distributor)
+			}
+		}
+		return nil
+	})
+	return changed, err
+}
+
+func expireInScope(tx Tx, now time.Time) []string {
+	changed := []string{}
+
+	//parents first, so that their sub-distributors are materialized against their up to date permissions
+	distributors := tx.List()
+	depth := make(map[string]int, len(distributors))
+	for _, distributor := range distributors {
+		lineage, _ := getLineage(tx, distributor)
+		depth[distributor] = len(lineage)
+	}
+	sort.Slice(distributors, func(i, j int) bool {
+		if depth[distributors[i]] != depth[distributors[j]] {
+			return depth[distributors[i]] < depth[distributors[j]]
+		}
+		return distributors[i] < distributors[j]
+	})
+
+	seen := make(map[string]bool)
+	for _, distributor := range distributors {
+		record, _ := tx.Get(distributor)
+		if !record.timed() {
+			continue
+		}
+		refreshed, recordChanged := materialize(tx, record, now)
+		if !recordChanged {
+			continue
+		}
+		tx.Put(distributor, refreshed)
+
+		for _, d := range append([]string{distributor}, cascadeToDescendants(tx, distributor, now)...) {
+			if !seen[d] {
+				seen[d] = true
+				changed = append(changed, d)
+			}
+		}
+	}
+	return changed
+}
+
+// StartExpiryScheduler runs ExpireContracts every interval, until stop is called.
+func (db *DataBank) StartExpiryScheduler(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				changed, err := db.ExpireContracts(now.UTC())
+				if err != nil {
+					log.Println("error expiring contracts:", err)
+				} else if len(changed) > 0 {
+					log.Println("contract validity changed the permissions of:", changed)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// excludeFromTimedPermissions removes the region from the base and every grant of the record, so that it doesn't come back when they're materialized.
+func excludeFromTimedPermissions(record Record, region regions.Region) Record {
+	base := record.basePermissions().Exclude(region).DTO()
+	record.Base = &base
+
+	grants := make([]Grant, len(record.Grants))
+	for i, grant := range record.Grants {
+		grant.Permissions = permissions.FromDTO(grant.Permissions).Exclude(region).DTO()
+		grants[i] = grant
+	}
+	record.Grants = grants
+	return record
+}
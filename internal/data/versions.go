@@ -0,0 +1,193 @@
+package data
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/permissions"
+	"challenge16/internal/response"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	VERSION_NOT_FOUND = "VERSION_NOT_FOUND"
+)
+
+type (
+	// ContractVersion is a contract applied on a distributor. Versions are never modified once recorded.
+	ContractVersion struct {
+		Version    int          `json:"version"` //starting at 1
+		Text       string       `json:"text"`
+		Contract   dto.Contract `json:"contract"` //as parsed, before parent filtering
+		Mode       string       `json:"mode"`
+		AppliedAt  time.Time    `json:"applied_at"`
+		AppliedBy  string       `json:"applied_by,omitempty"`
+		RollbackOf int          `json:"rollback_of,omitempty"` //version restored, if this version is a rollback
+	}
+
+	// ContractOptions are the settings a contract is applied with.
+	ContractOptions struct {
+		Mode      string //ContractStrict or ContractLenient
+		Text      string //raw text of the contract, kept in its version
+		AppliedBy string
+
+		rollbackOf int
+		replayOf   int //version whose contract a rollback applies again on the way to the one it restores: it's neither recorded again nor cascaded
+	}
+)
+
+// copyContract returns a copy of the contract whose permission maps can be modified independently.
+func copyContract(contract dto.Contract) dto.Contract {
+	contract.Permissions = permissions.FromDTO(contract.Permissions).Copy().DTO()
+	contract.Lineage = append([]string(nil), contract.Lineage...)
+	return contract
+}
+
+func (version ContractVersion) toDTO(withText bool) dto.ContractVersionData {
+	included, excluded := permissions.FromDTO(version.Contract.Permissions).RegionLists()
+	data := dto.ContractVersionData{
+		Version:    version.Version,
+		Lineage:    version.Contract.Lineage,
+		Rules:      dto.RegionLists{Included: included, Excluded: excluded},
+		ValidFrom:  version.Contract.ValidFrom,
+		ValidUntil: version.Contract.ValidUntil,
+		Mode:       version.Mode,
+		AppliedAt:  version.AppliedAt,
+		AppliedBy:  version.AppliedBy,
+		RollbackOf: version.RollbackOf,
+	}
+	if withText {
+		data.Text = version.Text
+	}
+	return data
+}
+
+// GetContractVersions lists the contract versions of the distributor, on the film if given, or on the whole catalog.
+func (db *DataBank) GetContractVersions(distributor, film string) response.Response {
+	var (
+		recorded       []ContractVersion
+		filmExists, ok bool
+	)
+	db.store.View(func(view ReadTx) {
+		var scoped ReadTx
+		if scoped, filmExists = scopeView(view, film); filmExists {
+			if _, ok = scoped.Get(distributor); ok {
+				recorded = scoped.Versions(distributor)
+			}
+		}
+	})
+	if !filmExists {
+		return filmNotFoundResponse(film)
+	}
+	if !ok {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+
+	versions := make([]dto.ContractVersionData, len(recorded))
+	for i, version := range recorded {
+		versions[i] = version.toDTO(false)
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"distributor": distributor,
+		"versions":    versions,
+	})
+}
+
+// GetContractVersion returns contract version n of the distributor, on the film if given, or on the whole catalog.
+func (db *DataBank) GetContractVersion(distributor, film string, n int) response.Response {
+	var (
+		recorded       []ContractVersion
+		filmExists, ok bool
+	)
+	db.store.View(func(view ReadTx) {
+		var scoped ReadTx
+		if scoped, filmExists = scopeView(view, film); filmExists {
+			if _, ok = scoped.Get(distributor); ok {
+				recorded = scoped.Versions(distributor)
+			}
+		}
+	})
+	if !filmExists {
+		return filmNotFoundResponse(film)
+	}
+	if !ok {
+		return response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor))
+	}
+	if n < 1 || n > len(recorded) {
+		return response.CreateError(404, VERSION_NOT_FOUND, fmt.Errorf("distributor %s has no contract version %d", distributor, n))
+	}
+	return response.CreateSuccess(200, "SUCCESS", recorded[n-1].toDTO(true))
+}
+
+// RollbackContract restores the distributor's permissions as of contract version n: they're cleared, and the contracts that made them up
+// are applied again, oldest first. Every contract goes through the same hierarchy and parent checks as when it was first applied, against the current state,
+// and the rollback is recorded as a new version. Time-bound contracts that expired since are left out, unless version n is one.
+// The distributor gets back the parent it had as of version n: one placed under a parent since is top-level again.
+// With a film, the versions of the contracts on the film are rolled back instead of the ones on the whole catalog.
+func (db *DataBank) RollbackContract(distributor, film string, n int, options ContractOptions) response.Response {
+	var outcome contractOutcome
+	err := db.update(func(tx Tx) error {
+		scoped, err := scopeTx(tx, film)
+		if err != nil {
+			return err
+		}
+		record, ok := scoped.Get(distributor)
+		if !ok {
+			return abort(response.CreateError(404, DISTRIBUTOR_NOT_FOUND, fmt.Errorf("distributor %s not found", distributor)))
+		}
+		recorded := scoped.Versions(distributor)
+		if n < 1 || n > len(recorded) {
+			return abort(response.CreateError(404, VERSION_NOT_FOUND, fmt.Errorf("distributor %s has no contract version %d", distributor, n)))
+		}
+		replayed := versionsUpTo(recorded, n)
+
+		//the contracts replayed place it back in the hierarchy as it was as of version n, under the parent they name if any
+		record.Parent = ""
+		record.Permissions = permissions.New().DTO()
+		record.Base, record.Grants = nil, nil
+		record.Exclusive = false
+		scoped.Put(distributor, record)
+
+		now := time.Now().UTC()
+		warnings, conflicts := []dto.ContractViolation{}, []dto.ExclusivityConflict{}
+		for i, version := range replayed {
+			replay := options
+			replay.Text = version.Text
+			if i == len(replayed)-1 {
+				replay.rollbackOf = n
+			} else if version.Contract.ValidUntil != nil && !now.Before(*version.Contract.ValidUntil) {
+				continue
+			} else {
+				replay.replayOf = version.Version
+			}
+
+			if outcome, err = applyContract(scoped, copyContract(version.Contract), replay); err != nil {
+				return err
+			}
+			warnings = append(warnings, outcome.warnings...)
+			conflicts = append(conflicts, outcome.conflicts...)
+		}
+		outcome.warnings, outcome.conflicts = warnings, conflicts
+		return nil
+	})
+	if err != nil {
+		return transactionErrorResponse(err)
+	}
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"version":              outcome.version,
+		"affected_descendants": outcome.affected,
+		"warnings":             outcome.warnings,
+		"conflicts":            outcome.conflicts,
+	})
+}
+
+// versionsUpTo returns the versions whose contracts made up the permissions as of version n, oldest first: every version up to n,
+// from the last rollback on, which stands for the versions it restored.
+func versionsUpTo(versions []ContractVersion, n int) []ContractVersion {
+	for i := n - 1; i >= 0; i-- {
+		if rollbackOf := versions[i].RollbackOf; rollbackOf > 0 {
+			return append(versionsUpTo(versions, rollbackOf), versions[i+1:n]...)
+		}
+	}
+	return slices.Clone(versions[:n])
+}
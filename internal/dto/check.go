@@ -0,0 +1,21 @@
+package dto
+
+import "challenge16/internal/regions"
+
+type (
+	PermissionCheck struct {
+		Distributor string `json:"distributor" validate:"required"`
+		Region      string `json:"region" validate:"required"`
+	}
+
+	// PermissionCheckResult is the outcome of one check of a batch: either its status or the error that prevented it.
+	PermissionCheckResult struct {
+		Distributor string `json:"distributor"`
+		Region      string `json:"region"`
+		Status      string `json:"status,omitempty"`
+		ErrorCode   string `json:"error_code,omitempty"`
+		Error       string `json:"error,omitempty"`
+		//the regions an ambiguous name may refer to
+		Candidates []regions.Candidate `json:"candidates,omitempty"`
+	}
+)
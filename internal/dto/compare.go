@@ -0,0 +1,10 @@
+package dto
+
+// PermissionComparison splits the regions held by two distributors, each part written as permissions.
+type PermissionComparison struct {
+	A     string      `json:"a"`
+	B     string      `json:"b"`
+	OnlyA RegionLists `json:"only_a"`
+	OnlyB RegionLists `json:"only_b"`
+	Both  RegionLists `json:"both"`
+}
@@ -1,6 +1,9 @@
 package dto
 
-import "challenge16/internal/regions"
+import (
+	"challenge16/internal/regions"
+	"time"
+)
 
 type (
 	// Contract struct {
@@ -19,6 +22,11 @@ type (
 
 		ParentDistributor *string
 		ContractRecipient string
+		Lineage           []string //recipient first, followed by its ancestors as given in the heading
+		ValidFrom         *time.Time
+		ValidUntil        *time.Time //exclusive
+		Film              string     //id of the film the contract is for, empty for the whole catalog
+		Exclusive         bool       //the recipient is to hold its rights exclusively
 		Permissions
 	}
 
@@ -82,3 +90,36 @@ func (c *Contract) AddExcludedRegion(regionString string) error {
 	}
 	return nil
 }
+
+type CoveredCity struct {
+	Code     string `json:"code"` //CITY-PROVINCE-COUNTRY
+	Name     string `json:"name"`
+	Province string `json:"province"`
+	Country  string `json:"country"`
+}
+
+type GetCitiesData struct {
+	Distributor    string         `json:"distributor"`
+	Page           int            `json:"page"`
+	PageSize       int            `json:"page_size"`
+	Total          int            `json:"total"` //number of cities covered, across all the pages
+	Cities         []CoveredCity  `json:"cities"`
+	ProvinceCounts map[string]int `json:"province_counts"` //PROVINCE-COUNTRY -> number of cities covered, across all the pages
+}
+
+// VanishedRegion is a region a distributor's rules reference, that a new region catalog doesn't have.
+type VanishedRegion struct {
+	Distributor string `json:"distributor"`
+	Film        string `json:"film,omitempty"` //the film of the rules, if not the whole catalog
+	Region      string `json:"region"`
+	Rule        string `json:"rule"` //INCLUDE or EXCLUDE
+}
+
+// RegionReloadData is the outcome of a reload of the region catalog.
+type RegionReloadData struct {
+	Source    string           `json:"source"` //the file the catalog was loaded from
+	Countries int              `json:"countries"`
+	Provinces int              `json:"provinces"`
+	Cities    int              `json:"cities"`
+	Vanished  []VanishedRegion `json:"vanished"`
+}
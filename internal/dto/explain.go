@@ -0,0 +1,32 @@
+package dto
+
+type (
+	// RuleMatch is a permission rule, written the way it appears in a contract (e.g. "EXCLUDE: KA-IN").
+	RuleMatch struct {
+		Rule  string `json:"rule"`
+		Level string `json:"level"` //country, province or city
+	}
+
+	// AncestorDecision is the decision on the same region for an ancestor of the distributor.
+	AncestorDecision struct {
+		Distributor string      `json:"distributor"`
+		Decision    string      `json:"decision"`
+		Rules       []RuleMatch `json:"rules"`
+		Exceptions  []RuleMatch `json:"exceptions,omitempty"`
+	}
+
+	ExplainPermissionData struct {
+		Distributor string `json:"distributor"`
+		Region      string `json:"region"`
+		Decision    string `json:"decision"`
+
+		// Rules are the distributor's rules on the region or on the regions containing it, coarsest first. The last one decides the region as a whole.
+		Rules []RuleMatch `json:"rules"`
+		// Exceptions are the rules on sub-regions that go against that decision, making it partial.
+		Exceptions []RuleMatch `json:"exceptions,omitempty"`
+
+		Ancestors []AncestorDecision `json:"ancestors,omitempty"`
+		// RestrictedBy is the top-most ancestor that doesn't fully hold the region, when the distributor doesn't either.
+		RestrictedBy *AncestorDecision `json:"restricted_by,omitempty"`
+	}
+)
@@ -0,0 +1,8 @@
+package dto
+
+// FilmData is a title of the catalog, along with the distributors holding rights on it.
+type FilmData struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Distributors []string `json:"distributors"`
+}
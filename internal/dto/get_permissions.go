@@ -5,3 +5,8 @@ type GetPermissionsData struct {
 	Included    []string `json:"included"`
 	Excluded    []string `json:"excluded"`
 }
+
+type NormalizePermissionsData struct {
+	GetPermissionsData
+	Changed bool `json:"changed"` //whether the stored rules were rewritten
+}
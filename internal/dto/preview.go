@@ -0,0 +1,82 @@
+package dto
+
+import "time"
+
+type (
+	RegionLists struct {
+		Included []string `json:"included"`
+		Excluded []string `json:"excluded"`
+	}
+
+	// TrimmedLine is an INCLUDE line of a contract that the parent filter cut down,
+	// because the parent doesn't hold all (Trimmed: "fully") or part (Trimmed: "partially") of the region.
+	TrimmedLine struct {
+		Line    string `json:"line"`
+		Trimmed string `json:"trimmed"`
+	}
+
+	ContractPreview struct {
+		DryRun      bool        `json:"dry_run"`
+		Distributor string      `json:"distributor"`
+		Permissions RegionLists `json:"permissions"` //resulting permissions of the recipient
+
+		// Gained and Lost are the regions the recipient would gain and lose, written as permissions.
+		Gained RegionLists `json:"gained"`
+		Lost   RegionLists `json:"lost"`
+
+		Trimmed             []TrimmedLine         `json:"trimmed"`
+		Warnings            []ContractViolation   `json:"warnings"`
+		Conflicts           []ExclusivityConflict `json:"conflicts"`
+		AffectedDescendants []string              `json:"affected_descendants"`
+	}
+
+	// ContractViolation is an INCLUDE line of a sub-distributor's contract for a region its parent doesn't hold.
+	ContractViolation struct {
+		Line   string `json:"line"`
+		Reason string `json:"reason"`
+		// BlockedBy is the parent's rule denying the region, nil when the parent has no rule on it at all.
+		BlockedBy *RuleMatch `json:"blocked_by,omitempty"`
+	}
+)
+
+type ContractVersionData struct {
+	Version    int         `json:"version"`
+	Text       string      `json:"text,omitempty"`
+	Lineage    []string    `json:"lineage"` //as given in the heading
+	Rules      RegionLists `json:"rules"`
+	ValidFrom  *time.Time  `json:"valid_from,omitempty"`
+	ValidUntil *time.Time  `json:"valid_until,omitempty"` //exclusive
+	Mode       string      `json:"mode"`
+	AppliedAt  time.Time   `json:"applied_at"`
+	AppliedBy  string      `json:"applied_by,omitempty"`
+	RollbackOf int         `json:"rollback_of,omitempty"`
+}
+
+// ExclusivityConflict is a distributor outside the lineage of the recipient of a change, holding some of the regions the recipient
+// would gain, while one of them holds its rights exclusively.
+type ExclusivityConflict struct {
+	Distributor string      `json:"distributor"`
+	Exclusive   bool        `json:"exclusive"` //whether Distributor holds its rights exclusively
+	Overlap     RegionLists `json:"overlap"`   //the regions both would hold
+}
+
+// ContractResult is what happened to one contract of a document, listed in the order the contracts were applied in.
+type ContractResult struct {
+	Block       int    `json:"block"` //position of the contract in the document, starting at 1
+	Line        int    `json:"line"`  //line of its heading
+	Distributor string `json:"distributor"`
+	Film        string `json:"film,omitempty"`
+
+	// Status is "applied", or when the document was rejected, "failed" for the contract that was,
+	// "rolled_back" for the ones applied before it and "skipped" for the ones after.
+	Status string `json:"status"`
+
+	Version             int                   `json:"version,omitempty"`
+	AffectedDescendants []string              `json:"affected_descendants,omitempty"`
+	Warnings            []ContractViolation   `json:"warnings,omitempty"`
+	Conflicts           []ExclusivityConflict `json:"conflicts,omitempty"`
+
+	ResponseCode string      `json:"resp_code,omitempty"` //why the contract failed
+	Error        string      `json:"error,omitempty"`
+	Details      interface{} `json:"details,omitempty"` //the data of the failure, such as the violations of the parent's permissions
+}
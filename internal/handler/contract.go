@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"challenge16/internal/data"
+	"challenge16/internal/response"
+	"challenge16/utils/validation"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// filmQuery selects the versions of the contracts on a film, instead of the ones on the whole catalog.
+type filmQuery struct {
+	Film string `query:"film"`
+}
+
+func (h *handler) GetContractVersions(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	if distributor == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor is required")).WriteToJSON(c)
+	}
+
+	req := new(filmQuery)
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.GetContractVersions(distributor, req.Film)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) GetContractVersion(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	n, err := c.ParamsInt("n")
+	if distributor == "" || err != nil {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor and version number are required")).WriteToJSON(c)
+	}
+
+	req := new(filmQuery)
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.GetContractVersion(distributor, req.Film, n)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) RollbackContract(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	n, err := c.ParamsInt("n")
+	if distributor == "" || err != nil {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor and version number are required")).WriteToJSON(c)
+	}
+
+	req := new(struct {
+		filmQuery
+		Mode string `query:"mode" validate:"omitempty,oneof=strict lenient"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	if req.Mode == "" {
+		req.Mode = data.ContractStrict
+	}
+
+	resp := h.databank.RollbackContract(distributor, req.Film, n, data.ContractOptions{
+		Mode:      req.Mode,
+		AppliedBy: appliedBy(c),
+	})
+	return resp.WriteToJSON(c)
+}
+
+// appliedBy returns who applies a contract. Fiber's header values are only valid during the request, and this one is stored.
+func appliedBy(c *fiber.Ctx) string {
+	return strings.Clone(c.Get(APPLIED_BY_HEADER))
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"challenge16/internal/data"
 	"challenge16/internal/response"
 	"challenge16/utils/validation"
 	"errors"
@@ -27,7 +28,37 @@ func (h *handler) RemoveDistributor(c *fiber.Ctx) error {
 		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor is required")).WriteToJSON(c)
 	}
 
-	resp := h.databank.RemoveDistributor(distributor)
+	req := new(struct {
+		Policy string `query:"policy" validate:"omitempty,oneof=restrict cascade orphan-revoke"`
+	})
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	if req.Policy == "" {
+		req.Policy = data.RemovalRestrict
+	}
+
+	resp := h.databank.RemoveDistributor(distributor, req.Policy)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) GetDistributorLineage(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	if distributor == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor is required")).WriteToJSON(c)
+	}
+
+	resp := h.databank.GetLineage(distributor)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) GetDistributorChildren(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	if distributor == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor is required")).WriteToJSON(c)
+	}
+
+	resp := h.databank.GetChildren(distributor)
 	return resp.WriteToJSON(c)
 }
 
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"challenge16/internal/response"
+	"challenge16/utils/validation"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *handler) AddFilm(c *fiber.Ctx) error {
+	req := new(struct {
+		ID    string `json:"id" validate:"required,excludesall= <"`
+		Title string `json:"title" validate:"required"`
+	})
+
+	if ok, err := validation.BindAndValidateJSONRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.AddFilm(req.ID, req.Title)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) GetFilms(c *fiber.Ctx) error {
+	resp := h.databank.GetFilms()
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) GetFilm(c *fiber.Ctx) error {
+	id := c.Params("film")
+	if id == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("film is required")).WriteToJSON(c)
+	}
+
+	resp := h.databank.GetFilm(id)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) UpdateFilm(c *fiber.Ctx) error {
+	id := c.Params("film")
+	if id == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("film is required")).WriteToJSON(c)
+	}
+
+	req := new(struct {
+		Title string `json:"title" validate:"required"`
+	})
+	if ok, err := validation.BindAndValidateJSONRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.UpdateFilm(id, req.Title)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) RemoveFilm(c *fiber.Ctx) error {
+	id := c.Params("film")
+	if id == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("film is required")).WriteToJSON(c)
+	}
+
+	resp := h.databank.RemoveFilm(id)
+	return resp.WriteToJSON(c)
+}
@@ -4,14 +4,17 @@ import "challenge16/internal/data"
 
 const (
 	URL_PARAM_MISSING = "URL_PARAM_MISSING"
+
+	// APPLIED_BY_HEADER names who applies a contract, to be recorded in its version
+	APPLIED_BY_HEADER = "X-Applied-By"
 )
 
 type handler struct {
-	databank data.DataBank
+	databank *data.DataBank
 }
 
-func NewHandler() *handler {
+func NewHandler(databank *data.DataBank) *handler {
 	return &handler{
-		databank: data.NewDataBank(),
+		databank: databank,
 	}
 }
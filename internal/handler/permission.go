@@ -1,12 +1,13 @@
 package handler
 
 import (
+	"challenge16/internal/contracts"
+	"challenge16/internal/data"
 	"challenge16/internal/dto"
-	"challenge16/internal/regions"
 	"challenge16/internal/response"
 	"challenge16/utils/validation"
 	"errors"
-	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -17,13 +18,115 @@ type checkPermissionRequest struct {
 }
 
 func (h *handler) CheckIfDistributionIsAllowed(c *fiber.Ctx) error {
+	req := new(struct {
+		checkPermissionRequest
+		At   string `query:"at" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		AsOf string `query:"as_of" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00,excluded_with=At"`
+		Film string `query:"film"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	region, ok, err := resolveRegion(c, req.RegionString)
+	if !ok {
+		return err
+	}
+
+	if req.AsOf != "" {
+		asOf, _ := time.Parse(time.RFC3339, req.AsOf) //validated above
+		resp := h.databank.CheckIfDistributionWasAllowed(req.Distributor, region, req.Film, asOf)
+		return resp.WriteToJSON(c)
+	}
+
+	at := time.Now().UTC()
+	if req.At != "" {
+		t, _ := time.Parse(time.RFC3339, req.At) //validated above
+		at = t
+	}
+
+	resp := h.databank.CheckIfDistributionIsAllowed(req.Distributor, region, req.Film, at)
+	return resp.WriteToJSON(c)
+}
+
+// CheckPermissionBatch accepts either a list of distributor/region pairs, or one distributor with a list of regions.
+func (h *handler) CheckPermissionBatch(c *fiber.Ctx) error {
+	req := new(struct {
+		Checks      []dto.PermissionCheck `json:"checks" validate:"required_without=Distributor,excluded_with=Distributor,dive"`
+		Distributor string                `json:"distributor" validate:"required_without=Checks"`
+		Regions     []string              `json:"regions" validate:"required_with=Distributor,excluded_with=Checks,dive,required"`
+	})
+
+	if ok, err := validation.BindAndValidateJSONRequest(c, req); !ok {
+		return err
+	}
+
+	checks := req.Checks
+	if req.Distributor != "" {
+		checks = make([]dto.PermissionCheck, len(req.Regions))
+		for i, region := range req.Regions {
+			checks[i] = dto.PermissionCheck{Distributor: req.Distributor, Region: region}
+		}
+	}
+
+	resp := h.databank.CheckBatch(checks)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) ExplainPermission(c *fiber.Ctx) error {
 	req := new(checkPermissionRequest)
 
 	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
 		return err
 	}
+	region, ok, err := resolveRegion(c, req.RegionString)
+	if !ok {
+		return err
+	}
+
+	resp := h.databank.ExplainPermission(req.Distributor, region)
+	return resp.WriteToJSON(c)
+}
+
+func (h *handler) ComparePermissions(c *fiber.Ctx) error {
+	req := new(struct {
+		A    string `query:"a" validate:"required"`
+		B    string `query:"b" validate:"required"`
+		Film string `query:"film"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.ComparePermissions(req.A, req.B, req.Film)
+	return resp.WriteToJSON(c)
+}
 
-	resp := h.databank.CheckIfDistributionIsAllowed(req.Distributor, req.RegionString)
+func (h *handler) GetDistributorCities(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	if distributor == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, errors.New("distributor is required")).WriteToJSON(c)
+	}
+
+	req := new(struct {
+		Country  string `query:"country" validate:"required_with=Province"`
+		Province string `query:"province"`
+		Page     int    `query:"page" validate:"omitempty,min=1"`
+		PageSize int    `query:"page_size" validate:"omitempty,min=1,max=1000"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 100
+	}
+
+	resp := h.databank.GetDistributorCities(distributor, req.Country, req.Province, req.Page, req.PageSize)
 	return resp.WriteToJSON(c)
 }
 
@@ -37,7 +140,21 @@ func (h *handler) AllowDistribution(c *fiber.Ctx) error {
 		return err
 	}
 
-	resp := h.databank.MarkInclusion(req.Distributor, req.RegionString)
+	query := new(struct {
+		Mode string `query:"mode" validate:"omitempty,oneof=strict lenient"`
+	})
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, query); !ok {
+		return err
+	}
+	if query.Mode == "" {
+		query.Mode = data.ContractStrict
+	}
+	region, ok, err := resolveRegion(c, req.RegionString)
+	if !ok {
+		return err
+	}
+
+	resp := h.databank.MarkInclusion(req.Distributor, region, query.Mode)
 	return resp.WriteToJSON(c)
 }
 
@@ -50,140 +167,97 @@ func (h *handler) DisallowDistribution(c *fiber.Ctx) error {
 	if ok, err := validation.BindAndValidateJSONRequest(c, req); !ok {
 		return err
 	}
+	region, ok, err := resolveRegion(c, req.RegionString)
+	if !ok {
+		return err
+	}
 
-	resp := h.databank.MarkExclusion(req.Distributor, req.RegionString)
+	resp := h.databank.MarkExclusion(req.Distributor, region)
 	return resp.WriteToJSON(c)
 }
 
 func (h *handler) ApplyContract(c *fiber.Ctx) error {
+	req := new(struct {
+		DryRun bool   `query:"dry_run"`
+		Mode   string `query:"mode" validate:"omitempty,oneof=strict lenient"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
 	contractText := string(c.Body())
-	contract, err := getContractData(contractText)
+	contract, err := contracts.Parse(contractText)
 	if err != nil {
-		if strings.HasPrefix(err.Error(), regions.InvalidRegionPrefix) {
-			return response.Response{
-				HttpStatusCode: 404,
-				ResponseCode:   "REGION_NOT_FOUND",
-				Error:          err,
-			}.WriteToJSON(c)
-		}
-		return response.Response{
-			HttpStatusCode: 400,
-			ResponseCode:   "INVALID_CONTRACT",
-			Error:          err,
-		}.WriteToJSON(c)
+		return contractErrorResponse(err).WriteToJSON(c)
 	}
-	resp := h.databank.ApplyContract(*contract)
+
+	if req.Mode == "" {
+		req.Mode = data.ContractStrict
+	}
+	options := data.ContractOptions{
+		Mode:      req.Mode,
+		Text:      contractText,
+		AppliedBy: appliedBy(c),
+	}
+
+	if req.DryRun {
+		return h.databank.PreviewContract(*contract, options).WriteToJSON(c)
+	}
+	resp := h.databank.ApplyContract(*contract, options)
 	return resp.WriteToJSON(c)
 }
 
-func getContractData(contractText string) (*dto.Contract, error) {
-	//Example contract:
-	/*
-		Permissions for DISTRIBUTOR1
-		INCLUDE: IN
-		INCLUDE: UN
-		EXCLUDE: KA-IN
-		EXCLUDE: CENAI-TN-IN
-	*/
-
-	//or
-
-	/*
-		Permissions for DISTRIBUTOR1 < DISTRIBUTOR2 < DISTRIBUTOR3
-		INCLUDE: YADGR-KA-IN
-	*/
-	var (
-		contract = dto.Contract{
-			Permissions: dto.Permissions{
-				IncludedCountries: make(map[string]bool),
-				IncludedProvinces: make(map[string]map[string]bool),
-				IncludedCities:    make(map[string]map[string]map[string]bool),
-				ExcludedProvinces: make(map[string]map[string]bool),
-				ExcludedCities:    make(map[string]map[string]map[string]bool),
-			},
-		}
-		err error
-	)
-	contractData := strings.Split(contractText, "\n")
-
-	if len(contractData) < 2 {
-		err = errors.New("Invalid contract, regions not found")
-		return nil, err
-	}
-	heading := strings.TrimLeft(contractData[0], " ")
-	if !strings.HasPrefix(heading, "Permissions for ") {
-		err = errors.New("Invalid contract, heading line: Prefix: 'Permissions for ' not found")
-		return nil, err
-	}
-
-	distributorHeirarchyText := strings.TrimPrefix(heading, "Permissions for ")
-	distributorHeirarchyText = strings.ReplaceAll(distributorHeirarchyText, " ", "") //Remove spaces for space-typo tolerance (extra spaces)
-	distributorHeirarchy := strings.Split(distributorHeirarchyText, "<")
-	switch len(distributorHeirarchy) {
-	case 0:
-		return nil, errors.New("Invalid contract, distributor(s) not found in heading line after 'Permissions for': " + distributorHeirarchyText)
-	case 1:
-		if distributorHeirarchy[0] == "" {
-			return nil, errors.New("Invalid contract, distributor(s) not found in heading line after 'Permissions for': " + distributorHeirarchyText)
-		}
-		contract.ContractRecipient = distributorHeirarchy[0]
-	default:
-		contract.ParentDistributor = &distributorHeirarchy[1]
-		contract.ContractRecipient = distributorHeirarchy[0]
+// ApplyContracts applies a document of several contracts, all of them or none.
+func (h *handler) ApplyContracts(c *fiber.Ctx) error {
+	req := new(struct {
+		Mode string `query:"mode" validate:"omitempty,oneof=strict lenient"`
+	})
+
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
 	}
 
-	//check for duplication in distributor heirarchy, also check for empty strings
-	distributorMap := make(map[string]bool)
-	for _, distributor := range distributorHeirarchy {
-		if distributor == "" {
-			return nil, errors.New("Invalid contract, empty distributor found in heading line after 'Permissions for': " + distributorHeirarchyText)
-		}
-		if _, ok := distributorMap[distributor]; ok {
-			return nil, errors.New("Invalid contract, duplicate distributor found in heading line after 'Permissions for': " + distributorHeirarchyText)
-		}
-		distributorMap[distributor] = true
-	}
-
-	for _, data := range contractData[1:] {
-		data = strings.TrimLeft(data, " ")
-		switch {
-		case strings.HasPrefix(data, "INCLUDE:"):
-			data = strings.TrimPrefix(data, "INCLUDE:")
-			data = strings.ReplaceAll(data, " ", "") //for space-typo tolerance (extra spaces)
-			err = contract.AddIncludedRegion(data)
-			if err != nil {
-				return nil, err
-			}
-
-		case strings.HasPrefix(data, "EXCLUDE:"):
-			line := data
-			data = strings.TrimPrefix(data, "EXCLUDE:")
-			data = strings.ReplaceAll(data, " ", "") //for space-typo tolerance (extra spaces)
-			if !strings.Contains(data, "-") {
-				//only country is mentioned
-				if !regions.CheckCountry(data) {
-					return nil, errors.New(regions.InvalidRegionPrefix + data)
-				} else {
-					return nil, errors.New("excluding a country(line:'" + line + "') is meaningless since there's no world-level inclusion to exclude from")
-				}
-			}
-
-			err = contract.AddExcludedRegion(data)
-			if err != nil {
-				return nil, err
-			}
-		case data == "": //empty line
-			continue
-		default:
-			return nil, errors.New("Invalid contract, invalid line found: " + data)
+	blocks, err := contracts.ParseDocument(string(c.Body()))
+	if err != nil {
+		return contractErrorResponse(err).WriteToJSON(c)
+	}
+
+	document := make([]data.DocumentContract, len(blocks))
+	for i, block := range blocks {
+		document[i] = data.DocumentContract{
+			Contract: *block.Contract,
+			Text:     block.Text,
+			Line:     block.Line,
 		}
 	}
 
-	if len(contract.IncludedCountries) == 0 && len(contract.IncludedProvinces) == 0 && len(contract.IncludedCities) == 0 {
-		return nil, errors.New("Invalid contract, no included regions found in contract")
+	if req.Mode == "" {
+		req.Mode = data.ContractStrict
+	}
+	options := data.ContractOptions{
+		Mode:      req.Mode,
+		AppliedBy: appliedBy(c),
+	}
+	return h.databank.ApplyContracts(document, options).WriteToJSON(c)
+}
+
+// contractErrorResponse is the response to a contract that doesn't parse, listing every mistake in it.
+// A contract whose only mistakes are regions missing from the catalog is answered with REGION_NOT_FOUND.
+func contractErrorResponse(err error) response.Response {
+	var errs contracts.ErrorList
+	if !errors.As(err, &errs) {
+		return response.CreateError(400, "INVALID_CONTRACT", err)
 	}
 
-	return &contract, nil
+	resp := response.CreateError(400, "INVALID_CONTRACT", errs)
+	if errs.UnknownRegionsOnly() {
+		resp = response.CreateError(404, "REGION_NOT_FOUND", errs)
+	}
+	resp.Data = map[string]interface{}{
+		"errors": errs,
+	}
+	return resp
 }
 
 func (h *handler) GetDistributorPermissions(c *fiber.Ctx) error {
@@ -192,11 +266,42 @@ func (h *handler) GetDistributorPermissions(c *fiber.Ctx) error {
 		return response.InvalidURLParamResponse("distributor", errors.New("distributor not found in url")).WriteToJSON(c)
 	}
 
+	req := new(struct {
+		AsOf string `query:"as_of" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+		Film string `query:"film"`
+	})
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	var asOf *time.Time
+	if req.AsOf != "" {
+		t, _ := time.Parse(time.RFC3339, req.AsOf) //validated above
+		asOf = &t
+	}
+
 	if c.Query("type", "text") == "json" {
-		resp := h.databank.GetDistributorPermissionAsJSON(distributor)
+		resp := h.databank.GetDistributorPermissionAsJSON(distributor, req.Film, asOf)
 		return resp.WriteToJSON(c)
 	} else {
-		note := h.databank.GetDistributorPermissionsAsText(distributor)
+		note := h.databank.GetDistributorPermissionsAsText(distributor, req.Film, asOf)
 		return c.Status(200).SendString(note)
 	}
 }
+
+// NormalizePermissions rewrites the stored rules of the distributor into their minimal equivalent form.
+func (h *handler) NormalizePermissions(c *fiber.Ctx) error {
+	distributor := c.Params("distributor")
+	if distributor == "" {
+		return response.InvalidURLParamResponse("distributor", errors.New("distributor not found in url")).WriteToJSON(c)
+	}
+
+	req := new(struct {
+		Film string `query:"film"`
+	})
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	resp := h.databank.NormalizePermissions(distributor, req.Film)
+	return resp.WriteToJSON(c)
+}
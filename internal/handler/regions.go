@@ -3,17 +3,46 @@ package handler
 import (
 	"challenge16/internal/regions"
 	"challenge16/internal/response"
+	"challenge16/utils/validation"
+	"errors"
 	"fmt"
+	"net/url"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 const (
-	INVALID_REGION = "INVALID_REGION"
+	INVALID_REGION   = "INVALID_REGION"
+	AMBIGUOUS_REGION = "AMBIGUOUS_REGION"
 )
 
+// regionListingRequest is the order of a region listing: by code (the default) or by name.
+type regionListingRequest struct {
+	Sort string `query:"sort" validate:"omitempty,oneof=name code"`
+}
+
+// resolveRegion returns the code of a region written with names. If the name is ambiguous, the response listing the candidates
+// is written and false is returned. A region that isn't found is returned as is, for the DataBank to report.
+func resolveRegion(c *fiber.Ctx, regionString string) (string, bool, error) {
+	code, err := regions.Resolve(regionString)
+	var ambiguous *regions.AmbiguousRegionError
+	if errors.As(err, &ambiguous) {
+		resp := response.CreateError(400, AMBIGUOUS_REGION, err)
+		resp.Data = map[string]interface{}{
+			"candidates": ambiguous.Candidates,
+		}
+		return "", false, resp.WriteToJSON(c)
+	}
+	return code, true, nil
+}
+
 func (h *handler) GetCountries(c *fiber.Ctx) error {
-	countries := regions.GetCountries()
+	req := new(regionListingRequest)
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	countries := regions.GetCountries(req.Sort)
 	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
 		"countries": countries,
 	}).WriteToJSON(c)
@@ -28,7 +57,12 @@ func (h *handler) GetProvincesInCountry(c *fiber.Ctx) error {
 		return response.CreateError(400, INVALID_REGION, fmt.Errorf("Invalid country code")).WriteToJSON(c)
 	}
 
-	provinces := regions.GetProvincesInCountry(countryCode)
+	req := new(regionListingRequest)
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	provinces := regions.GetProvincesInCountry(countryCode, req.Sort)
 	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
 		"provinces": provinces,
 	}).WriteToJSON(c)
@@ -46,8 +80,61 @@ func (h *handler) GetCitiesInProvince(c *fiber.Ctx) error {
 	if !regions.CheckProvince(countryCode, provinceCode) {
 		return response.CreateError(400, INVALID_REGION, fmt.Errorf("Invalid province code")).WriteToJSON(c)
 	}
-	cities := regions.GetCitiesInProvince(countryCode, provinceCode)
+	req := new(regionListingRequest)
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+
+	cities := regions.GetCitiesInProvince(countryCode, provinceCode, req.Sort)
 	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
 		"cities": cities,
 	}).WriteToJSON(c)
 }
+
+// SearchRegions finds regions by the start of their name or code, tolerating typos, for autocompletion.
+func (h *handler) SearchRegions(c *fiber.Ctx) error {
+	req := new(struct {
+		Query   string `query:"q" validate:"required"`
+		Type    string `query:"type" validate:"omitempty,oneof=country province city"`
+		Country string `query:"country"`
+		Limit   int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	})
+	if ok, err := validation.BindAndValidateURLQueryRequest(c, req); !ok {
+		return err
+	}
+	if req.Country != "" && !regions.CheckCountry(req.Country) {
+		return response.CreateError(400, INVALID_REGION, fmt.Errorf("Invalid country code")).WriteToJSON(c)
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	hits := regions.Search(req.Query, req.Type, req.Country, req.Limit)
+	return response.CreateSuccess(200, "SUCCESS", map[string]interface{}{
+		"regions": hits,
+	}).WriteToJSON(c)
+}
+
+// GetRegionDistributors lists the distributors holding rights in a region, which may be written with names, escaped in the path.
+func (h *handler) GetRegionDistributors(c *fiber.Ctx) error {
+	region, err := url.PathUnescape(c.Params("region"))
+	if err != nil {
+		return response.CreateError(400, INVALID_REGION, err).WriteToJSON(c)
+	}
+	if region == "" {
+		return response.CreateError(400, URL_PARAM_MISSING, fmt.Errorf("Region is required")).WriteToJSON(c)
+	}
+	region, ok, err := resolveRegion(c, region)
+	if !ok {
+		return err
+	}
+
+	resp := h.databank.GetRegionDistributors(region)
+	return resp.WriteToJSON(c)
+}
+
+// ReloadRegions reloads the region catalog from its file.
+func (h *handler) ReloadRegions(c *fiber.Ctx) error {
+	resp := h.databank.ReloadRegions()
+	return resp.WriteToJSON(c)
+}
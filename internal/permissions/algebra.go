@@ -0,0 +1,157 @@
+package permissions
+
+/*
+Set operations.
+
+A PermissionSet marks regions as included or excluded at country, province and city level,
+and a region is covered if the nearest marked region (itself, then its province, then its country) is included.
+So two sets can be combined region by region by evaluating both only at the regions either of them marks:
+every other region is covered exactly like its nearest marked ancestor.
+*/
+
+// Covers tells whether the region (empty provinceCode/cityCode for a country/province) is covered by the permissions.
+func (p PermissionSet) Covers(countryCode, provinceCode, cityCode string) bool {
+	if cityCode != "" {
+		if p.excludedCities[countryCode][provinceCode][cityCode] {
+			return false
+		}
+		if p.includedCities[countryCode][provinceCode][cityCode] {
+			return true
+		}
+	}
+	if provinceCode != "" {
+		if p.excludedProvinces[countryCode][provinceCode] {
+			return false
+		}
+		if p.includedProvinces[countryCode][provinceCode] {
+			return true
+		}
+	}
+	return p.includedCountries[countryCode]
+}
+
+// MarkedRegions adds every country, province and city that p marks to into, as country -> province -> cities.
+func (p PermissionSet) MarkedRegions(into map[string]map[string]map[string]bool) {
+	addProvince := func(country, province string) map[string]bool {
+		if into[country] == nil {
+			into[country] = make(map[string]map[string]bool)
+		}
+		if into[country][province] == nil {
+			into[country][province] = make(map[string]bool)
+		}
+		return into[country][province]
+	}
+
+	for country := range p.includedCountries {
+		if into[country] == nil {
+			into[country] = make(map[string]map[string]bool)
+		}
+	}
+	for _, provinceMaps := range []map[string]map[string]bool{p.includedProvinces, p.excludedProvinces} {
+		for country, provinces := range provinceMaps {
+			for province := range provinces {
+				addProvince(country, province)
+			}
+		}
+	}
+	for _, cityMaps := range []map[string]map[string]map[string]bool{p.includedCities, p.excludedCities} {
+		for country, provinces := range cityMaps {
+			for province, cities := range provinces {
+				provinceCities := addProvince(country, province)
+				for city := range cities {
+					provinceCities[city] = true
+				}
+			}
+		}
+	}
+}
+
+// combinePermissions returns the permissions covering exactly the regions for which op(covered by a, covered by b) is true.
+// The result marks a region only where its coverage differs from its parent region's.
+func combinePermissions(a, b PermissionSet, op func(inA, inB bool) bool) PermissionSet {
+	marked := make(map[string]map[string]map[string]bool)
+	a.MarkedRegions(marked)
+	b.MarkedRegions(marked)
+
+	result := New()
+	for country, provinces := range marked {
+		countryCovered := op(a.Covers(country, "", ""), b.Covers(country, "", ""))
+		if countryCovered {
+			result.includedCountries[country] = true
+		}
+
+		for province, cities := range provinces {
+			provinceCovered := op(a.Covers(country, province, ""), b.Covers(country, province, ""))
+			if provinceCovered != countryCovered {
+				target := result.excludedProvinces
+				if provinceCovered {
+					target = result.includedProvinces
+				}
+				setProvince(target, country, province)
+			}
+
+			for city := range cities {
+				cityCovered := op(a.Covers(country, province, city), b.Covers(country, province, city))
+				if cityCovered != provinceCovered {
+					target := result.excludedCities
+					if cityCovered {
+						target = result.includedCities
+					}
+					setCity(target, country, province, city)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Union returns the regions covered by p or by other.
+func (p PermissionSet) Union(other PermissionSet) PermissionSet {
+	return combinePermissions(p, other, func(inP, inOther bool) bool { return inP || inOther })
+}
+
+// Intersect returns the regions covered by both p and other.
+func (p PermissionSet) Intersect(other PermissionSet) PermissionSet {
+	return combinePermissions(p, other, func(inP, inOther bool) bool { return inP && inOther })
+}
+
+// Subtract returns the regions covered by p but not by other.
+func (p PermissionSet) Subtract(other PermissionSet) PermissionSet {
+	return combinePermissions(p, other, func(inP, inOther bool) bool { return inP && !inOther })
+}
+
+// IsEmpty tells whether p covers no region at all.
+func (p PermissionSet) IsEmpty() bool {
+	for _, included := range p.includedCountries {
+		if included {
+			return false
+		}
+	}
+	for _, provinces := range p.includedProvinces {
+		for _, included := range provinces {
+			if included {
+				return false
+			}
+		}
+	}
+	for _, provinces := range p.includedCities {
+		for _, cities := range provinces {
+			for _, included := range cities {
+				if included {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Equal tells whether p and other cover the same regions, however they are marked.
+func (p PermissionSet) Equal(other PermissionSet) bool {
+	return combinePermissions(p, other, func(inP, inOther bool) bool { return inP != inOther }).IsEmpty()
+}
+
+// IsSubsetOf tells whether every region p covers is covered by other.
+func (p PermissionSet) IsSubsetOf(other PermissionSet) bool {
+	return p.Subtract(other).IsEmpty()
+}
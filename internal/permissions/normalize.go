@@ -0,0 +1,135 @@
+package permissions
+
+import "challenge16/internal/regions"
+
+/*
+Normalization.
+
+The same regions can be marked in many ways: every city of a province, or the province; every province but one of a country,
+or the country with the other province excluded. Normalize rewrites a set against the region catalog (regions.Countries())
+into the fewest INCLUDE/EXCLUDE lines, preferring the coarser form when two take as many lines.
+It never excludes a region while including parts of it, so that the result can always be written as a contract.
+Rules on regions that are not in the catalog are kept as they are, exclusions as long as they're within an inclusion:
+a region that vanished from the catalog may be back with the next one, and the rules on it with it.
+*/
+
+// Normalize returns the set covering the same regions of the catalog as p, in its most compact form, along with the rules of p on regions the catalog lacks.
+func (p PermissionSet) Normalize() PermissionSet {
+	result := New()
+	marked := make(map[string]map[string]map[string]bool)
+	p.MarkedRegions(marked)
+
+	catalog := regions.Countries()
+	for countryCode := range marked {
+		country, exists := catalog[countryCode]
+		if !exists {
+			continue
+		}
+
+		var (
+			//lines needed with the country not included, and with it included
+			withoutCountry, withCountry = 0, 1
+			provinces                   = make(map[string]provinceCoverage, len(country.Provinces))
+		)
+		for provinceCode, province := range country.Provinces {
+			coverage := provinceCoverage{}
+			for cityCode := range province.Cities {
+				if p.Covers(countryCode, provinceCode, cityCode) {
+					coverage.covered = append(coverage.covered, cityCode)
+				} else {
+					coverage.uncovered = append(coverage.uncovered, cityCode)
+				}
+			}
+			provinces[provinceCode] = coverage
+
+			switch {
+			case len(coverage.uncovered) == 0:
+				withoutCountry++
+			case len(coverage.covered) == 0:
+				withCountry++
+			default:
+				withoutCountry += min(1+len(coverage.uncovered), len(coverage.covered))
+				withCountry += len(coverage.uncovered)
+			}
+		}
+
+		if withCountry <= withoutCountry {
+			result.includedCountries[countryCode] = true
+			for provinceCode, coverage := range provinces {
+				switch {
+				case len(coverage.uncovered) == 0:
+				case len(coverage.covered) == 0:
+					setProvince(result.excludedProvinces, countryCode, provinceCode)
+				default:
+					for _, cityCode := range coverage.uncovered {
+						setCity(result.excludedCities, countryCode, provinceCode, cityCode)
+					}
+				}
+			}
+			continue
+		}
+
+		for provinceCode, coverage := range provinces {
+			switch {
+			case len(coverage.covered) == 0:
+			case len(coverage.uncovered) == 0:
+				setProvince(result.includedProvinces, countryCode, provinceCode)
+			case 1+len(coverage.uncovered) <= len(coverage.covered):
+				setProvince(result.includedProvinces, countryCode, provinceCode)
+				for _, cityCode := range coverage.uncovered {
+					setCity(result.excludedCities, countryCode, provinceCode, cityCode)
+				}
+			default:
+				for _, cityCode := range coverage.covered {
+					setCity(result.includedCities, countryCode, provinceCode, cityCode)
+				}
+			}
+		}
+	}
+
+	//the rules on regions the catalog lacks, inclusions first, as exclusions are only kept within an inclusion
+	for countryCode := range p.includedCountries {
+		if _, exists := catalog[countryCode]; !exists {
+			result.includedCountries[countryCode] = true
+		}
+	}
+	for countryCode, provinces := range p.includedProvinces {
+		for provinceCode := range provinces {
+			if _, exists := catalog[countryCode].Provinces[provinceCode]; !exists {
+				setProvince(result.includedProvinces, countryCode, provinceCode)
+			}
+		}
+	}
+	for countryCode, provinces := range p.includedCities {
+		for provinceCode, cities := range provinces {
+			for cityCode := range cities {
+				if _, exists := catalog[countryCode].Provinces[provinceCode].Cities[cityCode]; !exists {
+					setCity(result.includedCities, countryCode, provinceCode, cityCode)
+				}
+			}
+		}
+	}
+	for countryCode, provinces := range p.excludedProvinces {
+		for provinceCode := range provinces {
+			if _, exists := catalog[countryCode].Provinces[provinceCode]; !exists && result.includedCountries[countryCode] {
+				setProvince(result.excludedProvinces, countryCode, provinceCode)
+			}
+		}
+	}
+	for countryCode, provinces := range p.excludedCities {
+		for provinceCode, cities := range provinces {
+			for cityCode := range cities {
+				_, exists := catalog[countryCode].Provinces[provinceCode].Cities[cityCode]
+				if !exists && (result.includedCountries[countryCode] || result.includedProvinces[countryCode][provinceCode]) {
+					setCity(result.excludedCities, countryCode, provinceCode, cityCode)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// provinceCoverage is which cities of a province a set covers.
+type provinceCoverage struct {
+	covered, uncovered []string
+}
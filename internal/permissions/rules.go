@@ -0,0 +1,106 @@
+package permissions
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"sort"
+)
+
+const (
+	// the decisions on a region
+	PARTIALLY_ALLOWED = "PARTIALLY_ALLOWED"
+	FULLY_ALLOWED     = "FULLY_ALLOWED"
+	FULLY_DENIED      = "FULLY_DENIED"
+)
+
+func includeRule(regionCode, level string) dto.RuleMatch {
+	return dto.RuleMatch{Rule: "INCLUDE: " + regionCode, Level: level}
+}
+
+func excludeRule(regionCode, level string) dto.RuleMatch {
+	return dto.RuleMatch{Rule: "EXCLUDE: " + regionCode, Level: level}
+}
+
+// MatchingRules returns the rules on the region and on the regions containing it, coarsest first.
+func (p PermissionSet) MatchingRules(region regions.Region) []dto.RuleMatch {
+	countryCode, provinceCode, cityCode := region.CountryCode, region.ProvinceCode, region.CityCode
+	rules := []dto.RuleMatch{}
+
+	if p.includedCountries[countryCode] {
+		rules = append(rules, includeRule(countryCode, regions.COUNTRY))
+	}
+	if region.Type == regions.COUNTRY {
+		return rules
+	}
+
+	provinceRegionCode := provinceCode + "-" + countryCode
+	if p.includedProvinces[countryCode][provinceCode] {
+		rules = append(rules, includeRule(provinceRegionCode, regions.PROVINCE))
+	}
+	if p.excludedProvinces[countryCode][provinceCode] {
+		rules = append(rules, excludeRule(provinceRegionCode, regions.PROVINCE))
+	}
+	if region.Type == regions.PROVINCE {
+		return rules
+	}
+
+	cityRegionCode := cityCode + "-" + provinceRegionCode
+	if p.includedCities[countryCode][provinceCode][cityCode] {
+		rules = append(rules, includeRule(cityRegionCode, regions.CITY))
+	}
+	if p.excludedCities[countryCode][provinceCode][cityCode] {
+		rules = append(rules, excludeRule(cityRegionCode, regions.CITY))
+	}
+	return rules
+}
+
+// ExceptionRules returns the rules on sub-regions of the region that change the coverage inherited from their parent region.
+// The region is partially allowed if and only if there is any.
+func (p PermissionSet) ExceptionRules(region regions.Region) []dto.RuleMatch {
+	countryCode := region.CountryCode
+	exceptions := []dto.RuleMatch{}
+	if region.Type == regions.CITY {
+		return exceptions
+	}
+
+	marked := make(map[string]map[string]map[string]bool)
+	p.MarkedRegions(marked)
+
+	for provinceCode, cities := range marked[countryCode] {
+		if region.Type == regions.PROVINCE && provinceCode != region.ProvinceCode {
+			continue
+		}
+
+		provinceCovered := p.Covers(countryCode, provinceCode, "")
+		if region.Type == regions.COUNTRY && provinceCovered != p.Covers(countryCode, "", "") {
+			if provinceCovered {
+				exceptions = append(exceptions, includeRule(provinceCode+"-"+countryCode, regions.PROVINCE))
+			} else {
+				exceptions = append(exceptions, excludeRule(provinceCode+"-"+countryCode, regions.PROVINCE))
+			}
+		}
+
+		for cityCode := range cities {
+			if cityCovered := p.Covers(countryCode, provinceCode, cityCode); cityCovered != provinceCovered {
+				if cityCovered {
+					exceptions = append(exceptions, includeRule(cityCode+"-"+provinceCode+"-"+countryCode, regions.CITY))
+				} else {
+					exceptions = append(exceptions, excludeRule(cityCode+"-"+provinceCode+"-"+countryCode, regions.CITY))
+				}
+			}
+		}
+	}
+	sort.Slice(exceptions, func(i, j int) bool { return exceptions[i].Rule < exceptions[j].Rule })
+	return exceptions
+}
+
+// Decide returns FULLY_ALLOWED, PARTIALLY_ALLOWED or FULLY_DENIED for the region.
+func (p PermissionSet) Decide(region regions.Region) string {
+	if len(p.ExceptionRules(region)) > 0 {
+		return PARTIALLY_ALLOWED
+	}
+	if p.Covers(region.CountryCode, region.ProvinceCode, region.CityCode) {
+		return FULLY_ALLOWED
+	}
+	return FULLY_DENIED
+}
@@ -0,0 +1,190 @@
+// Package permissions models the regions a distributor holds, as a PermissionSet, and the set algebra on them.
+package permissions
+
+import (
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"sort"
+)
+
+/*
+A PermissionSet marks regions as included or excluded at country, province and city level:
+
+	country->(if country is mentioned     ): -(excludedProvinces) -(excludedCities)
+	country->(if country is not mentioned ): +(includedProvinces - excludedCities) + (includedCities)
+
+and a region is covered if the nearest marked region (itself, then its province, then its country) is included.
+The same regions can be marked in many ways, which the set operations don't depend on: they work on the regions covered.
+
+A PermissionSet is a value sharing its maps: the operations never modify their operands, and return new sets.
+*/
+
+// PermissionSet is a set of regions, as the INCLUDE/EXCLUDE rules covering them.
+type PermissionSet struct {
+	includedCountries map[string]bool
+	includedProvinces map[string]map[string]bool
+	excludedProvinces map[string]map[string]bool
+	includedCities    map[string]map[string]map[string]bool
+	excludedCities    map[string]map[string]map[string]bool
+}
+
+// New returns an empty set.
+func New() PermissionSet {
+	return PermissionSet{
+		includedCountries: make(map[string]bool),
+		includedProvinces: make(map[string]map[string]bool),
+		excludedProvinces: make(map[string]map[string]bool),
+		includedCities:    make(map[string]map[string]map[string]bool),
+		excludedCities:    make(map[string]map[string]map[string]bool),
+	}
+}
+
+// FromDTO returns the set of the permissions. The maps are shared, not copied.
+func FromDTO(src dto.Permissions) PermissionSet {
+	dst := PermissionSet{
+		includedCountries: src.IncludedCountries,
+		includedProvinces: src.IncludedProvinces,
+		excludedProvinces: src.ExcludedProvinces,
+		includedCities:    src.IncludedCities,
+		excludedCities:    src.ExcludedCities,
+	}
+	if dst.includedCountries == nil || dst.includedProvinces == nil || dst.excludedProvinces == nil || dst.includedCities == nil || dst.excludedCities == nil {
+		//decoded from a document where some of the maps were null
+		return dst.Copy()
+	}
+	return dst
+}
+
+// Of returns the set covering exactly the region.
+func Of(region regions.Region) PermissionSet {
+	p := New()
+	switch region.Type {
+	case regions.COUNTRY:
+		p.includedCountries[region.CountryCode] = true
+	case regions.PROVINCE:
+		setProvince(p.includedProvinces, region.CountryCode, region.ProvinceCode)
+	case regions.CITY:
+		setCity(p.includedCities, region.CountryCode, region.ProvinceCode, region.CityCode)
+	}
+	return p
+}
+
+// DTO exposes the maps of the set in their exported form (for persisting). The maps are shared, not copied.
+func (p PermissionSet) DTO() dto.Permissions {
+	return dto.Permissions{
+		IncludedCountries: p.includedCountries,
+		IncludedProvinces: p.includedProvinces,
+		ExcludedProvinces: p.excludedProvinces,
+		IncludedCities:    p.includedCities,
+		ExcludedCities:    p.excludedCities,
+	}
+}
+
+// Copy returns a copy of the set that shares no map with it.
+func (p PermissionSet) Copy() PermissionSet {
+	dst := New()
+	for country, included := range p.includedCountries {
+		dst.includedCountries[country] = included
+	}
+	for _, maps := range [][2]map[string]map[string]bool{{dst.includedProvinces, p.includedProvinces}, {dst.excludedProvinces, p.excludedProvinces}} {
+		for country, provinces := range maps[1] {
+			maps[0][country] = make(map[string]bool, len(provinces))
+			for province, marked := range provinces {
+				maps[0][country][province] = marked
+			}
+		}
+	}
+	for _, maps := range [][2]map[string]map[string]map[string]bool{{dst.includedCities, p.includedCities}, {dst.excludedCities, p.excludedCities}} {
+		for country, provinces := range maps[1] {
+			maps[0][country] = make(map[string]map[string]bool, len(provinces))
+			for province, cities := range provinces {
+				maps[0][country][province] = make(map[string]bool, len(cities))
+				for city, marked := range cities {
+					maps[0][country][province][city] = marked
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Include returns the set with the region added.
+func (p PermissionSet) Include(region regions.Region) PermissionSet {
+	return p.Union(Of(region))
+}
+
+// Exclude returns the set with the region removed.
+func (p PermissionSet) Exclude(region regions.Region) PermissionSet {
+	return p.Subtract(Of(region))
+}
+
+// RegionLists returns the regions the set includes and excludes, as region codes, in a stable order:
+// countries, then provinces, then cities, each sorted by country, then province, then city code.
+func (p PermissionSet) RegionLists() (inclusions, exclusions []string) {
+	inclusions = append(countryCodes(p.includedCountries), provinceCodes(p.includedProvinces)...)
+	inclusions = append(inclusions, cityCodes(p.includedCities)...)
+	exclusions = append(provinceCodes(p.excludedProvinces), cityCodes(p.excludedCities)...)
+	return inclusions, exclusions
+}
+
+func countryCodes(countries map[string]bool) []string {
+	codes := []string{}
+	for _, country := range sortedKeys(countries) {
+		if countries[country] {
+			codes = append(codes, country)
+		}
+	}
+	return codes
+}
+
+func provinceCodes(provinces map[string]map[string]bool) []string {
+	codes := []string{}
+	for _, country := range sortedKeys(provinces) {
+		for _, province := range sortedKeys(provinces[country]) {
+			if provinces[country][province] {
+				codes = append(codes, province+"-"+country)
+			}
+		}
+	}
+	return codes
+}
+
+func cityCodes(cities map[string]map[string]map[string]bool) []string {
+	codes := []string{}
+	for _, country := range sortedKeys(cities) {
+		for _, province := range sortedKeys(cities[country]) {
+			for _, city := range sortedKeys(cities[country][province]) {
+				if cities[country][province][city] {
+					codes = append(codes, city+"-"+province+"-"+country)
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func setProvince(provinces map[string]map[string]bool, countryCode, provinceCode string) {
+	if provinces[countryCode] == nil {
+		provinces[countryCode] = make(map[string]bool)
+	}
+	provinces[countryCode][provinceCode] = true
+}
+
+func setCity(cities map[string]map[string]map[string]bool, countryCode, provinceCode, cityCode string) {
+	if cities[countryCode] == nil {
+		cities[countryCode] = make(map[string]map[string]bool)
+	}
+	if cities[countryCode][provinceCode] == nil {
+		cities[countryCode][provinceCode] = make(map[string]bool)
+	}
+	cities[countryCode][provinceCode][cityCode] = true
+}
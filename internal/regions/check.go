@@ -18,23 +18,35 @@ const (
 )
 
 func CheckCountry(countryCode string) bool {
-	_, ok := Countries[countryCode]
-	return ok
+	return current.Load().checkCountry(countryCode)
 }
 
 func CheckProvince(countryCode, provinceCode string) bool {
-	if CheckCountry(countryCode) == false {
+	return current.Load().checkProvince(countryCode, provinceCode)
+}
+
+func CheckCity(countryCode, provinceCode, cityCode string) bool {
+	return current.Load().checkCity(countryCode, provinceCode, cityCode)
+}
+
+func (c *Catalog) checkCountry(countryCode string) bool {
+	_, ok := c.countries[countryCode]
+	return ok
+}
+
+func (c *Catalog) checkProvince(countryCode, provinceCode string) bool {
+	if c.checkCountry(countryCode) == false {
 		return false
 	}
-	_, ok := Countries[countryCode].Provinces[provinceCode]
+	_, ok := c.countries[countryCode].Provinces[provinceCode]
 	return ok
 }
 
-func CheckCity(countryCode, provinceCode, cityCode string) bool {
-	if CheckProvince(countryCode, provinceCode) == false {
+func (c *Catalog) checkCity(countryCode, provinceCode, cityCode string) bool {
+	if c.checkProvince(countryCode, provinceCode) == false {
 		return false
 	}
-	_, ok := Countries[countryCode].Provinces[provinceCode].Cities[cityCode]
+	_, ok := c.countries[countryCode].Provinces[provinceCode].Cities[cityCode]
 	return ok
 }
 
@@ -46,6 +58,10 @@ type Region struct {
 }
 
 func GetRegionDetails(regionString string) (Region, error) {
+	return current.Load().regionDetails(regionString)
+}
+
+func (c *Catalog) regionDetails(regionString string) (Region, error) {
 	var (
 		region                                          Region
 		err                                             error
@@ -56,14 +72,14 @@ func GetRegionDetails(regionString string) (Region, error) {
 	case 1:
 		countryCode = subStrings[0]
 		regionType = COUNTRY
-		if !CheckCountry(countryCode) {
+		if !c.checkCountry(countryCode) {
 			err = errors.New(InvalidRegionPrefix + "country not found: " + countryCode)
 		}
 	case 2:
 		countryCode = subStrings[1]
 		provinceCode = subStrings[0]
 		regionType = PROVINCE
-		if !CheckProvince(countryCode, provinceCode) {
+		if !c.checkProvince(countryCode, provinceCode) {
 			err = errors.New(InvalidRegionPrefix + "country/province not found: " + countryCode + "-" + provinceCode)
 		}
 	default:
@@ -71,7 +87,7 @@ func GetRegionDetails(regionString string) (Region, error) {
 		provinceCode = subStrings[1]
 		cityCode = subStrings[0]
 		regionType = CITY
-		if !CheckCity(countryCode, provinceCode, cityCode) {
+		if !c.checkCity(countryCode, provinceCode, cityCode) {
 			err = errors.New(InvalidRegionPrefix + "country/province/city not found: " + countryCode + "-" + provinceCode + "-" + cityCode)
 		}
 	}
@@ -1,26 +1,35 @@
 package regions
 
+import "sort"
+
+const (
+	// SortByCode and SortByName are the orders region listings can be sorted in. Ties on name are broken by code.
+	SortByCode = "code"
+	SortByName = "name"
+)
+
 type regionInfo struct {
 	Name string `json:"name"`
 	Code string `json:"code"`
 }
 
-func GetCountries() []regionInfo {
-	countries := make([]regionInfo, 0, len(Countries))
-	for code, country := range Countries {
+func GetCountries(sortBy string) []regionInfo {
+	catalog := Countries()
+	countries := make([]regionInfo, 0, len(catalog))
+	for code, country := range catalog {
 		countries = append(countries, regionInfo{
 			Name: country.Name,
 			Code: code,
 		})
 	}
-	return countries
+	return sortRegions(countries, sortBy)
 }
 
-func GetProvincesInCountry(countryCode string) []regionInfo {
-	if !CheckCountry(countryCode) {
+func GetProvincesInCountry(countryCode, sortBy string) []regionInfo {
+	country, ok := Countries()[countryCode]
+	if !ok {
 		return nil
 	}
-	country := Countries[countryCode]
 	if country.Provinces == nil {
 		return nil
 	}
@@ -31,14 +40,14 @@ func GetProvincesInCountry(countryCode string) []regionInfo {
 			Code: code,
 		})
 	}
-	return provinces
+	return sortRegions(provinces, sortBy)
 }
 
-func GetCitiesInProvince(countryCode, provinceCode string) []regionInfo {
-	if !CheckProvince(countryCode, provinceCode) {
+func GetCitiesInProvince(countryCode, provinceCode, sortBy string) []regionInfo {
+	province, ok := Countries()[countryCode].Provinces[provinceCode]
+	if !ok {
 		return nil
 	}
-	province := Countries[countryCode].Provinces[provinceCode]
 	cities := make([]regionInfo, 0, len(province.Cities))
 	for code, name := range province.Cities {
 		cities = append(cities, regionInfo{
@@ -46,5 +55,16 @@ func GetCitiesInProvince(countryCode, provinceCode string) []regionInfo {
 			Code: code,
 		})
 	}
-	return cities
+	return sortRegions(cities, sortBy)
+}
+
+// sortRegions sorts the regions by name if sortBy is SortByName, and by code otherwise.
+func sortRegions(regions []regionInfo, sortBy string) []regionInfo {
+	sort.Slice(regions, func(i, j int) bool {
+		if sortBy == SortByName && regions[i].Name != regions[j].Name {
+			return regions[i].Name < regions[j].Name
+		}
+		return regions[i].Code < regions[j].Code
+	})
+	return regions
 }
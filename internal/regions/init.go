@@ -2,10 +2,18 @@ package regions
 
 import (
 	"challenge16/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode"
 )
 
 const (
 	filePath = "cities.csv"
+
+	// maxCatalogErrors is the number of invalid rows reported when a file is rejected.
+	maxCatalogErrors = 10
 )
 
 type (
@@ -18,43 +26,133 @@ type (
 		Name   string
 		Cities map[string]string
 	}
+
+	// Catalog is the set of regions loaded from a CSV file, along with their search index.
+	// A catalog is never modified once built: loading the regions again swaps in a new one, while the calls
+	// that started on the previous one finish on it.
+	Catalog struct {
+		countries map[string]countryData
+		index     searchIndex
+		source    string //the file it was loaded from
+	}
 )
 
-var Countries = make(map[string]countryData)
+// current is the catalog in use, read without locking.
+var current atomic.Pointer[Catalog]
 
+func init() {
+	current.Store(&Catalog{countries: make(map[string]countryData)})
+}
+
+// Countries returns the countries of the current catalog. They must be treated as read-only.
+func Countries() map[string]countryData {
+	return current.Load().countries
+}
+
+// Source returns the file the current catalog was loaded from, "" if none was loaded yet.
+func Source() string {
+	return current.Load().source
+}
+
+// LoadDataIntoMap loads the regions of the CSV file as the current catalog, in place of the previous one.
 func LoadDataIntoMap(csvFilePath string) error {
-	// Load data from CSV into the countries map
+	catalog, err := readCatalog(csvFilePath)
+	if err != nil {
+		return err
+	}
+	catalog.index = buildSearchIndex(catalog.countries)
+	Install(catalog)
+	return nil
+}
+
+// LoadCatalog reads and validates the regions of the CSV file into a new catalog, without installing it.
+func LoadCatalog(csvFilePath string) (*Catalog, error) {
+	catalog, err := readCatalog(csvFilePath)
+	if err != nil {
+		return nil, err
+	}
+	catalog.index = buildSearchIndex(catalog.countries)
+	return catalog, nil
+}
 
+// Install makes the catalog the current one.
+func Install(catalog *Catalog) {
+	current.Store(catalog)
+}
+
+// Counts returns the number of countries, provinces and cities of the catalog.
+func (c *Catalog) Counts() (countries, provinces, cities int) {
+	for _, country := range c.countries {
+		provinces += len(country.Provinces)
+		for _, province := range country.Provinces {
+			cities += len(province.Cities)
+		}
+	}
+	return len(c.countries), provinces, cities
+}
+
+// HasRegion tells whether the region, written with codes, is in the catalog.
+func (c *Catalog) HasRegion(regionString string) bool {
+	_, err := c.regionDetails(regionString)
+	return err == nil
+}
+
+// readCatalog reads the regions of the CSV file, rejecting the file if a row has no country or city code,
+// or a code that couldn't be written in a region ("-" separates the codes).
+func readCatalog(csvFilePath string) (*Catalog, error) {
 	datas, err := utils.ParseCSV(csvFilePath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(datas) == 0 {
+		return nil, fmt.Errorf("no region in %s", csvFilePath)
 	}
 
-	for _, data := range datas {
+	invalid := []string{}
+	countries := make(map[string]countryData)
+	for i, data := range datas {
+		if problem := invalidRow(data); problem != "" {
+			invalid = append(invalid, fmt.Sprintf("row %d: %s", i+2, problem)) //the header is row 1
+			continue
+		}
+
 		// Add data to the map
-		if _, ok := Countries[data.CountryCode]; !ok {
-			Countries[data.CountryCode] = countryData{
-				Name: data.CountryName,
-				Provinces: map[string]provinceData{
-					data.ProvinceCode: {
-						Name: data.ProvinceName,
-						Cities: map[string]string{
-							data.CityCode: data.CityName,
-						},
-					},
-				},
+		if _, ok := countries[data.CountryCode]; !ok {
+			countries[data.CountryCode] = countryData{
+				Name:      data.CountryName,
+				Provinces: make(map[string]provinceData),
 			}
-			continue
 		}
-		if _, ok := Countries[data.CountryCode].Provinces[data.ProvinceCode]; !ok {
-			Countries[data.CountryCode].Provinces[data.ProvinceCode] = provinceData{
+		if _, ok := countries[data.CountryCode].Provinces[data.ProvinceCode]; !ok {
+			countries[data.CountryCode].Provinces[data.ProvinceCode] = provinceData{
 				Name:   data.ProvinceName,
-				Cities: map[string]string{data.CityCode: data.CityName},
+				Cities: make(map[string]string),
 			}
-			continue
 		}
-		Countries[data.CountryCode].Provinces[data.ProvinceCode].Cities[data.CityCode] = data.CityName
+		countries[data.CountryCode].Provinces[data.ProvinceCode].Cities[data.CityCode] = data.CityName
 	}
 
-	return nil
+	if len(invalid) > 0 {
+		if len(invalid) > maxCatalogErrors {
+			invalid = append(invalid[:maxCatalogErrors], fmt.Sprintf("and %d more", len(invalid)-maxCatalogErrors))
+		}
+		return nil, errors.New("invalid regions in " + csvFilePath + ", " + strings.Join(invalid, "; "))
+	}
+	return &Catalog{countries: countries, source: csvFilePath}, nil
+}
+
+// invalidRow returns what's wrong with the row, "" if nothing is.
+func invalidRow(data utils.Data) string {
+	switch {
+	case data.CountryCode == "":
+		return "no country code"
+	case data.CityCode == "":
+		return "no city code"
+	}
+	for _, code := range []string{data.CityCode, data.ProvinceCode, data.CountryCode} {
+		if strings.ContainsFunc(code, func(r rune) bool { return r == '-' || unicode.IsSpace(r) }) {
+			return fmt.Sprintf("invalid code %q", code)
+		}
+	}
+	return ""
 }
@@ -0,0 +1,134 @@
+package regions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Candidate is a region a name may refer to, with its code and its full name.
+type Candidate struct {
+	Code string `json:"code"`
+	Name string `json:"name"` //"City, Province, Country"
+}
+
+// AmbiguousRegionError is the error resolving a name that matches several regions.
+type AmbiguousRegionError struct {
+	Region     string
+	Candidates []Candidate //sorted by code
+}
+
+func (e *AmbiguousRegionError) Error() string {
+	candidates := make([]string, len(e.Candidates))
+	for i, candidate := range e.Candidates {
+		candidates[i] = candidate.Code + " (" + candidate.Name + ")"
+	}
+	return fmt.Sprintf("ambiguous region %s, it matches %s", e.Region, strings.Join(candidates, ", "))
+}
+
+// Resolve returns the code of a region written with codes, names, or both, like KARNATAKA-INDIA or YELLAPUR-KA-IN.
+// Names are matched against cities.csv regardless of case and whitespace, and may contain hyphens themselves.
+// A name matching several regions is an *AmbiguousRegionError. A region matching none is returned as is, with the error of GetRegionDetails.
+func Resolve(regionString string) (string, error) {
+	c := current.Load()
+	_, err := c.regionDetails(regionString)
+	if err == nil {
+		return regionString, nil
+	}
+
+	matches := make(map[string]Candidate)
+	parts := strings.Split(regionString, "-")
+	//the country is made of the last parts, the province of the ones before if any, and the city of the rest
+	for countryStart := len(parts) - 1; countryStart >= 0; countryStart-- {
+		for _, countryCode := range c.matchingCountries(strings.Join(parts[countryStart:], "-")) {
+			country := c.countries[countryCode]
+			if countryStart == 0 {
+				matches[countryCode] = Candidate{Code: countryCode, Name: country.Name}
+				continue
+			}
+
+			for provinceStart := countryStart - 1; provinceStart >= 0; provinceStart-- {
+				for _, provinceCode := range c.matchingProvinces(countryCode, strings.Join(parts[provinceStart:countryStart], "-")) {
+					province := country.Provinces[provinceCode]
+					if provinceStart == 0 {
+						code := provinceCode + "-" + countryCode
+						matches[code] = Candidate{Code: code, Name: province.Name + ", " + country.Name}
+						continue
+					}
+
+					for _, cityCode := range c.matchingCities(countryCode, provinceCode, strings.Join(parts[:provinceStart], "-")) {
+						code := cityCode + "-" + provinceCode + "-" + countryCode
+						matches[code] = Candidate{Code: code, Name: province.Cities[cityCode] + ", " + province.Name + ", " + country.Name}
+					}
+				}
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return regionString, err
+	case 1:
+		for code := range matches {
+			return code, nil
+		}
+	}
+
+	candidates := make([]Candidate, 0, len(matches))
+	for _, candidate := range matches {
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Code < candidates[j].Code
+	})
+	return regionString, &AmbiguousRegionError{Region: regionString, Candidates: candidates}
+}
+
+// normalizeName folds the case and drops the whitespace of a name, so that "Tamil Nadu" and "TAMILNADU" match.
+func normalizeName(name string) string {
+	if !strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || unicode.ToUpper(r) != r }) {
+		return name //codes and most names in capitals already are
+	}
+	var normalized strings.Builder
+	normalized.Grow(len(name))
+	for _, r := range name {
+		if !unicode.IsSpace(r) {
+			normalized.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return normalized.String()
+}
+
+func (c *Catalog) matchingCountries(name string) []string {
+	name = normalizeName(name)
+	codes := []string{}
+	for code, country := range c.countries {
+		if name != "" && (name == normalizeName(code) || name == normalizeName(country.Name)) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func (c *Catalog) matchingProvinces(countryCode, name string) []string {
+	name = normalizeName(name)
+	codes := []string{}
+	for code, province := range c.countries[countryCode].Provinces {
+		if name != "" && (name == normalizeName(code) || name == normalizeName(province.Name)) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+func (c *Catalog) matchingCities(countryCode, provinceCode, name string) []string {
+	name = normalizeName(name)
+	codes := []string{}
+	for code, cityName := range c.countries[countryCode].Provinces[provinceCode].Cities {
+		if name != "" && (name == normalizeName(code) || name == normalizeName(cityName)) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,272 @@
+package regions
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MatchExact, MatchPrefix and MatchFuzzy are how a search hit matched the query, from the best to the worst.
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchFuzzy  = "fuzzy"
+
+	// fuzzyMinLength is the shortest query matched fuzzily: shorter ones would match almost anything within an edit.
+	fuzzyMinLength = 3
+
+	// unigramPositions and bigramPositions are how far into names their runes are indexed for fuzzyCandidates:
+	// as far as the pieces of a query can be found, with the edits Search allows.
+	unigramPositions = 5
+	bigramPositions  = 7
+)
+
+// SearchHit is a region matching a search.
+type SearchHit struct {
+	Code     string `json:"code"` //CITY-PROVINCE-COUNTRY, PROVINCE-COUNTRY or COUNTRY
+	Name     string `json:"name"` //"City, Province, Country"
+	Type     string `json:"type"` //country, province or city
+	Match    string `json:"match"`
+	Distance int    `json:"distance"` //edits between the query and the start of the name, for fuzzy hits
+}
+
+type (
+	// searchEntry is a region of the search index.
+	searchEntry struct {
+		hit     SearchHit
+		country string
+		name    []rune //the normalized name, for fuzzy matching
+	}
+
+	// searchKey is a string a region is found by: its normalized name, each word of it, its code, or the first part of its code.
+	searchKey struct {
+		key   string
+		entry int
+	}
+
+	// searchIndex holds every region of the catalog, with their keys sorted for prefix lookups,
+	// and the entries by the runes their names start with, to narrow down fuzzy matching.
+	searchIndex struct {
+		entries []searchEntry
+		keys    []searchKey
+		grams   map[uint64][]int32 //entries by gramKey, see fuzzyCandidates
+	}
+)
+
+// buildSearchIndex indexes the regions of a catalog.
+func buildSearchIndex(countries map[string]countryData) searchIndex {
+	regions := 0
+	for _, country := range countries {
+		regions++
+		for _, province := range country.Provinces {
+			regions += 1 + len(province.Cities)
+		}
+	}
+	//a region has three keys at least, and most names are a word or two
+	built := searchIndex{
+		entries: make([]searchEntry, 0, regions),
+		keys:    make([]searchKey, 0, 4*regions),
+		grams:   make(map[uint64][]int32),
+	}
+	keys := make([]string, 0, 8)
+	add := func(code, name, fullName, regionType, country string) {
+		entry := len(built.entries)
+		normalized := normalizeName(name)
+		runes := []rune(normalized)
+		built.entries = append(built.entries, searchEntry{
+			hit:     SearchHit{Code: code, Name: fullName, Type: regionType},
+			country: country,
+			name:    runes,
+		})
+		for position := 0; position < len(runes) && position < unigramPositions; position++ {
+			key := gramKey(runes[position], 0, position)
+			built.grams[key] = append(built.grams[key], int32(entry))
+		}
+		for position := 0; position+1 < len(runes) && position < bigramPositions; position++ {
+			key := gramKey(runes[position], runes[position+1], position)
+			built.grams[key] = append(built.grams[key], int32(entry))
+		}
+
+		keys = append(keys[:0], normalized, normalizeName(code), normalizeName(firstPart(code)))
+		if strings.ContainsFunc(name, isWordSeparator) {
+			for _, word := range strings.FieldsFunc(name, isWordSeparator) {
+				keys = append(keys, normalizeName(word))
+			}
+		}
+		for i, key := range keys {
+			if !slices.Contains(keys[:i], key) {
+				built.keys = append(built.keys, searchKey{key: key, entry: entry})
+			}
+		}
+	}
+
+	for countryCode, country := range countries {
+		add(countryCode, country.Name, country.Name, COUNTRY, countryCode)
+		for provinceCode, province := range country.Provinces {
+			provinceName := province.Name + ", " + country.Name
+			add(provinceCode+"-"+countryCode, province.Name, provinceName, PROVINCE, countryCode)
+			for cityCode, cityName := range province.Cities {
+				add(cityCode+"-"+provinceCode+"-"+countryCode, cityName, cityName+", "+provinceName, CITY, countryCode)
+			}
+		}
+	}
+
+	slices.SortFunc(built.keys, func(a, b searchKey) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return built
+}
+
+// firstPart returns the part of a code before its first hyphen: the region's own code, without its province and country.
+func firstPart(code string) string {
+	part, _, _ := strings.Cut(code, "-")
+	return part
+}
+
+func isWordSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '-'
+}
+
+// Search returns the regions whose name or code starts with the query, or whose name starts with something within an edit or two of it,
+// best matches first. regionType (COUNTRY, PROVINCE or CITY) and country restrict the search when given.
+func Search(query, regionType, country string, limit int) []SearchHit {
+	query = normalizeName(query)
+	if query == "" {
+		return []SearchHit{}
+	}
+	index := current.Load().index
+	wanted := func(entry searchEntry) bool {
+		return (regionType == "" || entry.hit.Type == regionType) && (country == "" || entry.country == country)
+	}
+
+	best := make(map[int]SearchHit)
+	for i := sort.Search(len(index.keys), func(i int) bool { return index.keys[i].key >= query }); i < len(index.keys); i++ {
+		key := index.keys[i]
+		if !strings.HasPrefix(key.key, query) {
+			break
+		}
+		entry := index.entries[key.entry]
+		if !wanted(entry) {
+			continue
+		}
+		hit := entry.hit
+		hit.Match = MatchPrefix
+		if key.key == query {
+			hit.Match = MatchExact
+		}
+		if previous, found := best[key.entry]; !found || rank(hit) < rank(previous) {
+			best[key.entry] = hit
+		}
+	}
+
+	if queryRunes := []rune(query); len(queryRunes) >= fuzzyMinLength {
+		maxEdits := 1
+		if len(queryRunes) >= 5 {
+			maxEdits = 2
+		}
+		for _, i := range index.fuzzyCandidates(queryRunes, maxEdits) {
+			entry := index.entries[i]
+			if _, found := best[i]; found || !wanted(entry) {
+				continue
+			}
+			if distance := prefixDistance(queryRunes, entry.name, maxEdits); distance <= maxEdits {
+				hit := entry.hit
+				hit.Match = MatchFuzzy
+				hit.Distance = distance
+				best[i] = hit
+			}
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(best))
+	for _, hit := range best {
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if rank(hits[i]) != rank(hits[j]) {
+			return rank(hits[i]) < rank(hits[j])
+		}
+		if hits[i].Name != hits[j].Name {
+			return hits[i].Name < hits[j].Name
+		}
+		return hits[i].Code < hits[j].Code
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// fuzzyCandidates returns the entries whose names may start with something within maxEdits (1 or 2) of the query, so that only those
+// are matched fuzzily, rather than every region of the catalog.
+// If the start of the query is split into maxEdits+1 pieces, one of them is left intact by the edits, and shifted by maxEdits positions at most:
+// the candidates are the names with the first runes of a piece around its position. The pieces are of a rune for the shortest queries,
+// and of two otherwise.
+func (index searchIndex) fuzzyCandidates(query []rune, maxEdits int) []int {
+	pieces := maxEdits + 1
+	length := min(len(query), 2*pieces) / pieces
+
+	seen := make([]bool, len(index.entries))
+	candidates := []int{}
+	for piece := 0; piece < pieces; piece++ {
+		offset := piece * length
+		second := rune(0)
+		if length == 2 {
+			second = query[offset+1]
+		}
+		for position := max(offset-maxEdits, 0); position <= offset+maxEdits; position++ {
+			for _, entry := range index.grams[gramKey(query[offset], second, position)] {
+				if !seen[entry] {
+					seen[entry] = true
+					candidates = append(candidates, int(entry))
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// gramKey is the key of the runes at a position of a name in searchIndex.grams: one rune (second is 0) or two.
+func gramKey(first, second rune, position int) uint64 {
+	return uint64(position)<<42 | uint64(first)<<21 | uint64(second)
+}
+
+// rank orders hits by how they matched, then by the edits they took.
+func rank(hit SearchHit) int {
+	switch hit.Match {
+	case MatchExact:
+		return 0
+	case MatchPrefix:
+		return 1
+	}
+	return 2 + hit.Distance
+}
+
+// prefixDistance returns the smallest edit distance between the query and a prefix of the name, so that a query typed halfway matches.
+// Distances above maxEdits aren't computed exactly: maxEdits+1 is returned instead.
+func prefixDistance(query, name []rune, maxEdits int) int {
+	//Levenshtein distances between the query and each prefix of the name, one row per character of the query
+	row := make([]int, len(name)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(query); i++ {
+		previous := row[0] //the distance of the prefixes one character shorter
+		row[0] = i
+		rowMin := row[0]
+		for j := 1; j <= len(name); j++ {
+			substitution := previous
+			if query[i-1] != name[j-1] {
+				substitution++
+			}
+			previous = row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, substitution)
+			rowMin = min(rowMin, row[j])
+		}
+		if rowMin > maxEdits {
+			return maxEdits + 1
+		}
+	}
+	return min(slices.Min(row), maxEdits+1)
+}
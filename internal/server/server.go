@@ -1,6 +1,7 @@
 package server
 
 import (
+	"challenge16/internal/data"
 	"challenge16/internal/handler"
 	"time"
 
@@ -9,7 +10,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
-func NewServer(rateLimit int) *fiber.App {
+func NewServer(rateLimit int, databank *data.DataBank) *fiber.App {
 	app := fiber.New()
 	app.Use(logger.New())
 	app.Use(limiter.New(limiter.Config{
@@ -17,7 +18,7 @@ func NewServer(rateLimit int) *fiber.App {
 		Expiration: 1 * time.Minute,
 	}))
 
-	handler := handler.NewHandler()
+	handler := handler.NewHandler(databank)
 
 	// Initialize the routes
 	{
@@ -27,25 +28,63 @@ func NewServer(rateLimit int) *fiber.App {
 			distributor.Post("/", handler.AddDistributor)
 			distributor.Delete("/:distributor", handler.RemoveDistributor)
 			distributor.Get("/", handler.GetDistributors)
+			distributor.Get("/:distributor/lineage", handler.GetDistributorLineage)
+			distributor.Get("/:distributor/children", handler.GetDistributorChildren)
+		}
+
+		// Film routes
+		film := app.Group("/film")
+		{
+			film.Post("/", handler.AddFilm)
+			film.Get("/", handler.GetFilms)
+			film.Get("/:film", handler.GetFilm)
+			film.Put("/:film", handler.UpdateFilm)
+			film.Delete("/:film", handler.RemoveFilm)
 		}
 
 		// Permission routes
 		permission := app.Group("/permission")
 		{
 			permission.Get("/check", handler.CheckIfDistributionIsAllowed)
+			permission.Post("/check/batch", handler.CheckPermissionBatch)
+			permission.Get("/explain", handler.ExplainPermission)
+			permission.Get("/compare", handler.ComparePermissions)
 			permission.Post("/allow", handler.AllowDistribution)
 			permission.Post("/contract", handler.ApplyContract)
+			permission.Post("/contracts", handler.ApplyContracts)
 			permission.Post("/disallow", handler.DisallowDistribution)
+			permission.Get("/:distributor/cities", handler.GetDistributorCities)
+			permission.Post("/:distributor/normalize", handler.NormalizePermissions)
 			permission.Get("/:distributor", handler.GetDistributorPermissions)
 		}
 
+		// Contract history routes
+		contract := app.Group("/contract")
+		{
+			contract.Get("/:distributor/versions", handler.GetContractVersions)
+			contract.Get("/:distributor/versions/:n", handler.GetContractVersion)
+			contract.Post("/:distributor/rollback/:n", handler.RollbackContract)
+		}
+
 		// Region routes
 		regions := app.Group("/regions")
 		{
 			regions.Get("/countries", handler.GetCountries)
+			regions.Get("/search", handler.SearchRegions)
 			regions.Get("/provinces/:countryCode", handler.GetProvincesInCountry)
 			regions.Get("/cities/:countryCode/:provinceCode", handler.GetCitiesInProvince)
 		}
+
+		region := app.Group("/region")
+		{
+			region.Get("/:region/distributors", handler.GetRegionDistributors)
+		}
+
+		// Admin routes
+		admin := app.Group("/admin")
+		{
+			admin.Post("/regions/reload", handler.ReloadRegions)
+		}
 	}
 
 	return app
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParentRevocationCascades(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	for _, contract := range []string{
+		`Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US`,
+		`Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN`,
+		`Permissions for DISTRIBUTOR3 < DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: KA-IN
+INCLUDE: CENAI-TN-IN`,
+		`Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: US`,
+	} {
+		statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"KA-IN"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.ElementsMatch(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR3"}, response.Data.(map[string]interface{})["affected_descendants"])
+
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	assert.ElementsMatch(t, []string{"KA-IN"}, permissions.Excluded)
+
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR3")
+	assert.ElementsMatch(t, []string{"CENAI-TN-IN"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+
+	// a revocation the sub-distributors aren't concerned with doesn't affect them
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR2","region":"GJ-IN"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Empty(t, response.Data.(map[string]interface{})["affected_descendants"])
+
+	// revoking a whole country
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"US"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.ElementsMatch(t, []interface{}{"DISTRIBUTOR4"}, response.Data.(map[string]interface{})["affected_descendants"])
+
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR4")
+	assert.Empty(t, permissions.Included)
+}
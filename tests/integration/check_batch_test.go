@@ -0,0 +1,51 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPermissionBatch(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN
+EXCLUDE: CENAI-TN-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/check/batch", "application/json", `{"checks":[
+		{"distributor":"DISTRIBUTOR1","region":"IN"},
+		{"distributor":"DISTRIBUTOR1","region":"GJ-IN"},
+		{"distributor":"DISTRIBUTOR1","region":"XX"},
+		{"distributor":"DISTRIBUTOR9","region":"IN"},
+		{"distributor":"DISTRIBUTOR1","region":"CENAI-TN-IN"}
+	]}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	results := response.Data.(map[string]interface{})["results"].([]interface{})
+	require.Len(t, results, 5)
+	assert.Equal(t, "PARTIALLY_ALLOWED", results[0].(map[string]interface{})["status"])
+	assert.Equal(t, "FULLY_ALLOWED", results[1].(map[string]interface{})["status"])
+	assert.Equal(t, REGION_NOT_FOUND, results[2].(map[string]interface{})["error_code"])
+	assert.Equal(t, DISTRIBUTOR_NOT_FOUND, results[3].(map[string]interface{})["error_code"])
+	assert.Equal(t, "DISTRIBUTOR9", results[3].(map[string]interface{})["distributor"])
+	assert.Equal(t, "FULLY_DENIED", results[4].(map[string]interface{})["status"])
+
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/check/batch", "application/json", `{"distributor":"DISTRIBUTOR1","regions":["KA-IN","US"]}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	results = response.Data.(map[string]interface{})["results"].([]interface{})
+	require.Len(t, results, 2)
+	assert.Equal(t, "FULLY_DENIED", results[0].(map[string]interface{})["status"])
+	assert.Equal(t, "US", results[1].(map[string]interface{})["region"])
+	assert.Equal(t, "FULLY_DENIED", results[1].(map[string]interface{})["status"])
+
+	// either checks, or a distributor with regions
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/check/batch", "application/json", `{}`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/check/batch", "application/json", `{"distributor":"DISTRIBUTOR1","regions":["IN"],"checks":[{"distributor":"DISTRIBUTOR1","region":"IN"}]}`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
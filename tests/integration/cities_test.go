@@ -0,0 +1,75 @@
+package test
+
+import (
+	"challenge16/internal/dto"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getCities(t *testing.T, ts *TestSetup, url string) (int, dto.GetCitiesData) {
+	statusCode, response := doRequest(t, ts.App, "GET", url, "", "")
+
+	return statusCode, decodeData[dto.GetCitiesData](t, response.Data)
+}
+
+func TestDistributorCities(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: DD-IN
+INCLUDE: DN-IN
+INCLUDE: GA-IN
+INCLUDE: CHNAR-CH-IN
+EXCLUDE: AMLFU-DN-IN
+EXCLUDE: PNAJI-GA-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, cities := getCities(t, ts, "/permission/DISTRIBUTOR1/cities")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, 1+2+1+44, cities.Total)
+	assert.Len(t, cities.Cities, 48)
+	assert.Equal(t, map[string]int{"CH-IN": 1, "DD-IN": 2, "DN-IN": 1, "GA-IN": 44}, cities.ProvinceCounts)
+	assert.Equal(t, dto.CoveredCity{Code: "CHNAR-CH-IN", Name: "Chandigarh", Province: "CH-IN", Country: "IN"}, cities.Cities[0])
+
+	// paginated, in a stable order
+	_, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?page=1&page_size=3")
+	assert.Equal(t, 48, cities.Total)
+	assert.Equal(t, []dto.CoveredCity{
+		{Code: "CHNAR-CH-IN", Name: "Chandigarh", Province: "CH-IN", Country: "IN"},
+		{Code: "DAMAN-DD-IN", Name: "Daman", Province: "DD-IN", Country: "IN"},
+		{Code: "DIAXV-DD-IN", Name: "Diu", Province: "DD-IN", Country: "IN"},
+	}, cities.Cities)
+
+	_, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?page=2&page_size=3")
+	require.NotEmpty(t, cities.Cities)
+	assert.Equal(t, "SILVA-DN-IN", cities.Cities[0].Code)
+
+	_, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?page=100")
+	assert.Empty(t, cities.Cities)
+
+	// pages too large to be multiplied by the page size are empty too
+	for _, page := range []string{"92233720368547759", "4611686018427387904"} {
+		statusCode, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?page_size=1000&page="+page)
+		assert.Equal(t, http.StatusOK, statusCode, page)
+		assert.Empty(t, cities.Cities, page)
+	}
+
+	// filtered by province
+	_, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?country=IN&province=DN")
+	assert.Equal(t, 1, cities.Total)
+	assert.Equal(t, map[string]int{"DN-IN": 1}, cities.ProvinceCounts)
+
+	_, cities = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?country=US")
+	assert.Zero(t, cities.Total)
+
+	statusCode, _ = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?province=DN")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = getCities(t, ts, "/permission/DISTRIBUTOR1/cities?country=IN&province=XX")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	statusCode, _ = getCities(t, ts, "/permission/DISTRIBUTOR9/cities")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
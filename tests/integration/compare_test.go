@@ -0,0 +1,49 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparePermissions(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+	app := ts.App
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: KA-IN
+INCLUDE: TN-IN
+INCLUDE: CHNAR-CH-IN
+INCLUDE: US`))
+
+	statusCode, response := doRequest(t, app, "GET", "/permission/compare?a=DISTRIBUTOR1&b=DISTRIBUTOR2", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	comparison := response.Data.(map[string]interface{})
+
+	assert.Equal(t, map[string]interface{}{
+		"included": []interface{}{"IN"},
+		"excluded": []interface{}{"CH-IN", "KA-IN", "TN-IN"},
+	}, comparison["only_a"])
+	assert.Equal(t, map[string]interface{}{
+		"included": []interface{}{"US", "KA-IN"},
+		"excluded": []interface{}{},
+	}, comparison["only_b"])
+	// Chandigarh is the only city of its province, so holding it is holding the province
+	assert.Equal(t, map[string]interface{}{
+		"included": []interface{}{"CH-IN", "TN-IN"},
+		"excluded": []interface{}{},
+	}, comparison["both"])
+
+	statusCode, response = doRequest(t, app, "GET", "/permission/compare?a=DISTRIBUTOR1&b=DISTRIBUTOR9", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "DISTRIBUTOR_NOT_FOUND", response.ResponseCode)
+
+	statusCode, _ = doRequest(t, app, "GET", "/permission/compare?a=DISTRIBUTOR1", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
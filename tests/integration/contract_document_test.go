@@ -0,0 +1,153 @@
+package test
+
+import (
+	"challenge16/internal/contracts"
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyDocument(t *testing.T, ts *TestSetup, mode, document string) (int, Response, []dto.ContractResult) {
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/contracts?mode="+mode, "text/plain", document)
+
+	results := decodeData[struct {
+		Results []dto.ContractResult `json:"results"`
+	}](t, response.Data)
+	return statusCode, response, results.Results
+}
+
+const bundle = `# sub-grants first, the master grant last
+Permissions for DISTRIBUTOR3 < DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: KA-IN
+
+Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN
+
+Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US
+`
+
+func TestApplyContractDocument(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _, results := applyDocument(t, ts, "strict", bundle)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// parents first
+	require.Len(t, results, 3)
+	for n, expected := range []struct {
+		block       int
+		line        int
+		distributor string
+	}{{3, 8, "DISTRIBUTOR1"}, {2, 5, "DISTRIBUTOR2"}, {1, 2, "DISTRIBUTOR3"}} {
+		assert.Equal(t, expected.block, results[n].Block)
+		assert.Equal(t, expected.line, results[n].Line)
+		assert.Equal(t, expected.distributor, results[n].Distributor)
+		assert.Equal(t, "applied", results[n].Status)
+		assert.Equal(t, 1, results[n].Version)
+	}
+
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"IN", "US"}, permissions.Included)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"IN"}, permissions.Included)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR3")
+	assert.Equal(t, []string{"KA-IN"}, permissions.Included)
+
+	// each version has the text of its own contract
+	statusCode, response := doRequest(t, ts.App, "GET", "/contract/DISTRIBUTOR2/versions/1", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "Permissions for DISTRIBUTOR2 < DISTRIBUTOR1\nINCLUDE: IN", response.Data.(map[string]interface{})["text"])
+}
+
+func TestApplyContractDocumentIsAllOrNothing(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, response, results := applyDocument(t, ts, "strict", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+
+Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US
+
+Permissions for DISTRIBUTOR3 < DISTRIBUTOR1
+INCLUDE: KA-IN`)
+	require.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+	assert.Contains(t, response.Error, "contract for DISTRIBUTOR2 on line 4")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "rolled_back", results[0].Status)
+	assert.Zero(t, results[0].Version)
+	assert.Equal(t, "failed", results[1].Status)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", results[1].ResponseCode)
+	assert.Contains(t, results[1].Details, "violations")
+	assert.Equal(t, "skipped", results[2].Status)
+
+	// nothing was applied
+	for _, distributor := range []string{"DISTRIBUTOR1", "DISTRIBUTOR2", "DISTRIBUTOR3"} {
+		statusCode, _ = getPermissions(t, ts.App, distributor)
+		assert.Equal(t, http.StatusNotFound, statusCode)
+	}
+
+	// lenient mode trims the line instead
+	statusCode, _, results = applyDocument(t, ts, "lenient", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+
+Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US
+INCLUDE: KA-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+	require.Len(t, results, 2)
+	require.Len(t, results[1].Warnings, 1)
+	assert.Equal(t, "INCLUDE: US", results[1].Warnings[0].Line)
+}
+
+func TestApplyContractDocumentErrors(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	// a cycle in the hierarchy
+	statusCode, response, _ := applyDocument(t, ts, "strict", `Permissions for DISTRIBUTOR1 < DISTRIBUTOR2
+INCLUDE: IN
+Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "INVALID_CONTRACT", response.ResponseCode)
+	assert.Contains(t, response.Error, "cycle")
+
+	// mistakes are reported for every contract, at their line in the document
+	statusCode, response, _ = applyDocument(t, ts, "strict", `INCLUDE: IN
+Permissions for DISTRIBUTOR1
+INCLUDE: IN
+Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE IN`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, response.Error, "line 1, column 1")
+	assert.Contains(t, response.Error, "line 5, column 9")
+
+	// the single contract endpoint takes a single contract
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", bundle)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Contains(t, response.Error, "line 5, column 1: expected a single contract")
+}
+
+func TestParseContractDocument(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	blocks, err := contracts.ParseDocument("Permissions for D1\r\nINCLUDE: IN\r\n# D2 resells Karnataka\r\n\r\nPermissions for D2 < D1 ON FILM: F1\r\nINCLUDE: KA-IN\r\n")
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, 1, blocks[0].Line)
+	assert.Equal(t, "Permissions for D1\r\nINCLUDE: IN\r\n# D2 resells Karnataka", blocks[0].Text)
+	assert.Equal(t, "D1", blocks[0].Contract.ContractRecipient)
+	assert.Equal(t, 5, blocks[1].Line)
+	assert.Equal(t, "F1", blocks[1].Contract.Film)
+	assert.Equal(t, []string{"D2", "D1"}, blocks[1].Contract.Lineage)
+}
@@ -0,0 +1,172 @@
+package test
+
+import (
+	"challenge16/internal/contracts"
+	"challenge16/internal/regions"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// positions returns the line and column of each error, in order.
+func positions(t *testing.T, err error) [][2]int {
+	var errs contracts.ErrorList
+	require.ErrorAs(t, err, &errs)
+	found := make([][2]int, len(errs))
+	for i, e := range errs {
+		found[i] = [2]int{e.Line, e.Column}
+	}
+	return found
+}
+
+func TestParseContract(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	contract, err := contracts.Parse("# renewed yearly\r\n" +
+		"Permissions for DISTRIBUTOR2 < DISTRIBUTOR1 ON FILM: F001\r\n" +
+		"\r\n" +
+		"INCLUDE: IN   # all of India\r\n" +
+		"EXCLUDE: KA-IN\r\n" +
+		"VALID FROM: 2025-01-01\r\n" +
+		"VALID UNTIL: 2025-06-30T18:30:00Z\r\n" +
+		"EXCLUSIVE: yes\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, "DISTRIBUTOR2", contract.ContractRecipient)
+	require.NotNil(t, contract.ParentDistributor)
+	assert.Equal(t, "DISTRIBUTOR1", *contract.ParentDistributor)
+	assert.Equal(t, []string{"DISTRIBUTOR2", "DISTRIBUTOR1"}, contract.Lineage)
+	assert.Equal(t, "F001", contract.Film)
+	assert.True(t, contract.Permissions.IncludedCountries["IN"])
+	assert.True(t, contract.Permissions.ExcludedProvinces["IN"]["KA"])
+	require.NotNil(t, contract.ValidFrom)
+	assert.Equal(t, "2025-01-01T00:00:00Z", contract.ValidFrom.Format("2006-01-02T15:04:05Z07:00"))
+	require.NotNil(t, contract.ValidUntil)
+	assert.Equal(t, "2025-06-30T18:30:00Z", contract.ValidUntil.Format("2006-01-02T15:04:05Z07:00"))
+	assert.True(t, contract.Exclusive)
+}
+
+func TestParseContractReportsEveryError(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	_, err := contracts.Parse(`Permissions for DISTRIBUTOR1
+INCLUDE:
+EXCLUDE KA-IN
+INCLUDE: US
+VALID FROM: someday
+EXCLUSIVE: MAYBE
+EXCLUSIVE: NO
+GRANT: FR`)
+	require.Error(t, err)
+	assert.Equal(t, [][2]int{
+		{2, 8},  // nothing after INCLUDE:
+		{3, 9},  // no colon after EXCLUDE
+		{5, 13}, // invalid time
+		{6, 12}, // neither YES nor NO
+		{7, 1},  // EXCLUSIVE given twice
+		{8, 1},  // unknown clause
+	}, positions(t, err))
+
+	var errs contracts.ErrorList
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs[0].Message, "expected a region after INCLUDE:")
+	assert.Contains(t, errs[1].Message, "expected ':' after EXCLUDE")
+	assert.Contains(t, errs[4].Message, "first given on line 6")
+	assert.False(t, errs.UnknownRegionsOnly())
+}
+
+func TestParseContractHeading(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	_, err := contracts.Parse(`Permissions for D1 < < D1
+INCLUDE: IN`)
+	assert.Equal(t, [][2]int{{1, 22}, {1, 23}}, positions(t, err))
+
+	_, err = contracts.Parse(`Permission for D1
+INCLUDE: IN`)
+	assert.Equal(t, [][2]int{{1, 1}}, positions(t, err))
+
+	// the missing inclusion is reported at the heading
+	_, err = contracts.Parse(`# comment only
+
+Permissions for D1
+EXCLUDE: KA-IN`)
+	assert.Equal(t, [][2]int{{3, 1}}, positions(t, err))
+
+	_, err = contracts.Parse("  # nothing but a comment\n")
+	assert.Equal(t, [][2]int{{1, 1}}, positions(t, err))
+}
+
+func TestParseContractUnknownRegions(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	_, err := contracts.Parse(`Permissions for D1
+INCLUDE: XX
+EXCLUDE:   KAA-IN`)
+	assert.Equal(t, [][2]int{{2, 10}, {3, 12}}, positions(t, err))
+
+	var errs contracts.ErrorList
+	require.ErrorAs(t, err, &errs)
+	assert.True(t, errs.UnknownRegionsOnly())
+}
+
+func TestApplyContractListsErrors(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN
+VALID UNTIL 2025-01-01
+EXCLUSIVE: SURE`)
+	require.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "INVALID_CONTRACT", response.ResponseCode)
+	assert.Contains(t, response.Error, "line 4, column 13")
+	assert.Equal(t, map[string]interface{}{
+		"errors": []interface{}{
+			map[string]interface{}{"line": float64(4), "column": float64(13), "message": "expected ':' after VALID UNTIL"},
+			map[string]interface{}{"line": float64(5), "column": float64(12), "message": `expected YES or NO after EXCLUSIVE:, found "SURE"`},
+		},
+	}, response.Data)
+
+	// nothing was applied
+	statusCode, _ = getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KAA-IN`)
+	require.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "REGION_NOT_FOUND", response.ResponseCode)
+}
+
+func TestParseContractReportsEveryContradiction(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	_, err := contracts.Parse(`Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: KA-IN
+EXCLUDE: TN-IN
+INCLUDE: US
+EXCLUDE:  CA-US
+INCLUDE: CA-US
+EXCLUDE: ZHAGN-35-CN
+VALID FROM: 2026-01-01
+VALID UNTIL: 2025-01-01`)
+	require.Error(t, err)
+	assert.Equal(t, [][2]int{
+		{3, 10}, // a province of an included country
+		{6, 11}, // included on line 7 too
+		{7, 10}, // a province of an included country
+		{8, 10}, // neither its country nor its province included
+		{10, 1}, // ends before it starts
+	}, positions(t, err))
+
+	var errs contracts.ErrorList
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs[0].Message, "the country is also included on line 2")
+	assert.Contains(t, errs[1].Message, "also included on line 7")
+	assert.Contains(t, errs[3].Message, "the country is not included and the province is not included")
+	assert.Contains(t, errs[4].Message, "it must start before it ends")
+}
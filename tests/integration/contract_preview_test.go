@@ -0,0 +1,70 @@
+package test
+
+import (
+	"challenge16/internal/dto"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func previewContract(t *testing.T, ts *TestSetup, mode, contract string) (int, dto.ContractPreview) {
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/contract?dry_run=true&mode="+mode, "text/plain", contract)
+
+	return statusCode, decodeData[dto.ContractPreview](t, response.Data)
+}
+
+func TestContractDryRun(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, preview := previewContract(t, ts, "lenient", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, dto.RegionLists{Included: []string{"IN"}, Excluded: []string{"KA-IN"}}, preview.Permissions)
+	assert.Equal(t, dto.RegionLists{Included: []string{"IN"}, Excluded: []string{"KA-IN"}}, preview.Gained)
+	assert.Empty(t, preview.Lost.Included)
+	assert.Equal(t, []dto.TrimmedLine{
+		{Line: "INCLUDE: IN", Trimmed: "partially"},
+		{Line: "INCLUDE: US", Trimmed: "fully"},
+	}, preview.Trimmed)
+	assert.Equal(t, []dto.ContractViolation{{Line: "INCLUDE: US", Reason: "DISTRIBUTOR1 doesn't hold US"}}, preview.Warnings)
+
+	// nothing was applied
+	statusCode, _ = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: TN-IN
+INCLUDE: GJ-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// contracts are merged into the current permissions
+	statusCode, preview = previewContract(t, ts, "strict", `Permissions for DISTRIBUTOR1
+INCLUDE: US
+INCLUDE: KA-IN`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, dto.RegionLists{Included: []string{"IN", "US"}, Excluded: []string{}}, preview.Permissions)
+	assert.Equal(t, dto.RegionLists{Included: []string{"US", "KA-IN"}, Excluded: []string{}}, preview.Gained)
+	assert.Empty(t, preview.Lost.Included)
+	assert.Empty(t, preview.Trimmed)
+	assert.Empty(t, preview.AffectedDescendants)
+
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.ElementsMatch(t, []string{"TN-IN", "GJ-IN"}, permissions.Included)
+
+	// errors are reported as they would be without dry_run
+	statusCode, _ = previewContract(t, ts, "strict", `Permissions for DISTRIBUTOR3 < DISTRIBUTOR9
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
@@ -16,6 +16,8 @@ const (
 	INVALID_CONTRACT             = "INVALID_CONTRACT"
 	PARENT_DISTRIBUTOR_NOT_FOUND = "PARENT_DISTRIBUTOR_NOT_FOUND"
 	DISTRIBUTOR_NOT_FOUND        = "DISTRIBUTOR_NOT_FOUND"
+	HIERARCHY_MISMATCH           = "HIERARCHY_MISMATCH"
+	REGION_NOT_FOUND             = "REGION_NOT_FOUND"
 )
 
 func TestApplyContractSelfValidation(t *testing.T) {
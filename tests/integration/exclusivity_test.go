@@ -0,0 +1,68 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExclusivityConflicts(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+	app := ts.App
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+EXCLUSIVE: YES
+INCLUDE: IN
+EXCLUDE: TN-IN`))
+
+	// an unrelated distributor can't get a region held exclusively
+	contract := `Permissions for DISTRIBUTOR2
+INCLUDE: KA-IN
+INCLUDE: TN-IN`
+	statusCode, response := doRequest(t, app, "POST", "/permission/contract", "text/plain", contract)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCLUSIVITY_CONFLICT", response.ResponseCode)
+	conflicts := response.Data.(map[string]interface{})["conflicts"].([]interface{})
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "DISTRIBUTOR1", conflicts[0].(map[string]interface{})["distributor"])
+	assert.Equal(t, []interface{}{"KA-IN"}, conflicts[0].(map[string]interface{})["overlap"].(map[string]interface{})["included"])
+
+	// unless in lenient mode, where the conflict is only reported
+	statusCode, response = doRequest(t, app, "POST", "/permission/contract?mode=lenient", "text/plain", contract)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Len(t, response.Data.(map[string]interface{})["conflicts"], 1)
+
+	// its own sub-distributors can
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR3 < DISTRIBUTOR1
+INCLUDE: KA-IN`))
+
+	// an exclusive contract can't take regions others already hold
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR4
+INCLUDE: US`))
+	statusCode, response = doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR5
+EXCLUSIVE: YES
+INCLUDE: US`)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCLUSIVITY_CONFLICT", response.ResponseCode)
+
+	// without exclusivity, sharing regions is fine
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR5
+INCLUDE: US`))
+
+	// inclusions are checked too
+	statusCode, _ = doRequest(t, app, "POST", "/permission/allow", "application/json", `{"distributor": "DISTRIBUTOR4", "region": "GA-IN"}`)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	statusCode, _ = doRequest(t, app, "POST", "/permission/allow", "application/json", `{"distributor": "DISTRIBUTOR4", "region": "TN-IN"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	statusCode, response = doRequest(t, app, "POST", "/permission/allow?mode=lenient", "application/json", `{"distributor": "DISTRIBUTOR4", "region": "GA-IN"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Len(t, response.Data.(map[string]interface{})["conflicts"], 1)
+
+	statusCode, _ = doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR6
+EXCLUSIVE: MAYBE
+INCLUDE: FR`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"challenge16/internal/dto"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func explainPermission(t *testing.T, ts *TestSetup, distributor, region string) (int, dto.ExplainPermissionData) {
+	statusCode, response := doRequest(t, ts.App, "GET", "/permission/explain?distributor="+distributor+"&region="+region, "", "")
+
+	return statusCode, decodeData[dto.ExplainPermissionData](t, response.Data)
+}
+
+func TestExplainPermission(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	for _, contract := range []string{
+		`Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN
+EXCLUDE: CENAI-TN-IN`,
+		`Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN`,
+	} {
+		statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+		require.Equal(t, http.StatusOK, statusCode)
+	}
+
+	statusCode, explanation := explainPermission(t, ts, "DISTRIBUTOR1", "YADGR-KA-IN")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_DENIED", explanation.Decision)
+	assert.Equal(t, []dto.RuleMatch{
+		{Rule: "INCLUDE: IN", Level: "country"},
+		{Rule: "EXCLUDE: KA-IN", Level: "province"},
+	}, explanation.Rules)
+	assert.Nil(t, explanation.RestrictedBy)
+
+	_, explanation = explainPermission(t, ts, "DISTRIBUTOR1", "TN-IN")
+	assert.Equal(t, "PARTIALLY_ALLOWED", explanation.Decision)
+	assert.Equal(t, []dto.RuleMatch{{Rule: "INCLUDE: IN", Level: "country"}}, explanation.Rules)
+	assert.Equal(t, []dto.RuleMatch{{Rule: "EXCLUDE: CENAI-TN-IN", Level: "city"}}, explanation.Exceptions)
+
+	_, explanation = explainPermission(t, ts, "DISTRIBUTOR1", "GJ-IN")
+	assert.Equal(t, "FULLY_ALLOWED", explanation.Decision)
+	assert.Empty(t, explanation.Exceptions)
+
+	// the sub-distributor is denied KA-IN because of its parent
+	_, explanation = explainPermission(t, ts, "DISTRIBUTOR2", "KA-IN")
+	assert.Equal(t, "FULLY_DENIED", explanation.Decision)
+	assert.Equal(t, []dto.RuleMatch{
+		{Rule: "INCLUDE: IN", Level: "country"},
+		{Rule: "EXCLUDE: KA-IN", Level: "province"},
+	}, explanation.Rules)
+	require.Len(t, explanation.Ancestors, 1)
+	require.NotNil(t, explanation.RestrictedBy)
+	assert.Equal(t, "DISTRIBUTOR1", explanation.RestrictedBy.Distributor)
+	assert.Equal(t, "FULLY_DENIED", explanation.RestrictedBy.Decision)
+
+	// a city in a country that isn't included at all
+	_, other := explainPermission(t, ts, "DISTRIBUTOR2", "ONATI-SS-ES")
+	assert.Equal(t, "FULLY_DENIED", other.Decision)
+	assert.Empty(t, other.Rules)
+
+	statusCode, response := doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR2&region=ONATI-SS-ES", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_DENIED", response.ResponseCode)
+
+	statusCode, _ = explainPermission(t, ts, "DISTRIBUTOR9", "IN")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	statusCode, _ = explainPermission(t, ts, "DISTRIBUTOR1", "XX")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
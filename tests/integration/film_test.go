@@ -0,0 +1,124 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionsPerFilm(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+	app, _ := openPersistedApp(t, dir, 0)
+
+	statusCode, _ := doRequest(t, app, "POST", "/film", "application/json", `{"id": "F1", "title": "The First Film"}`)
+	require.Equal(t, http.StatusCreated, statusCode)
+	statusCode, response := doRequest(t, app, "POST", "/film", "application/json", `{"id": "F1", "title": "Again"}`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "FILM_EXISTS", response.ResponseCode)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1 ON FILM:F1
+INCLUDE: IN`))
+	// the parent filter works within the film: DISTRIBUTOR1 holds US, but not on F1
+	statusCode, response = doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1 ON FILM:F1
+INCLUDE: US`)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1 ON FILM:F1
+INCLUDE: KA-IN`))
+
+	statusCode, response = doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1 ON FILM:F2
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "FILM_NOT_FOUND", response.ResponseCode)
+
+	// persisted along with the permissions
+	app, _ = openPersistedApp(t, dir, 0)
+
+	checks := []struct {
+		url        string
+		statusCode int
+		status     string
+	}{
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&film=F1", http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US&film=F1", http.StatusOK, "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN", http.StatusOK, "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US", http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR2&region=KA-IN&film=F1", http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR2&region=KA-IN", http.StatusOK, "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&film=F2", http.StatusNotFound, "FILM_NOT_FOUND"},
+	}
+	for _, check := range checks {
+		statusCode, response := doRequest(t, app, "GET", check.url, "", "")
+		assert.Equal(t, check.statusCode, statusCode, check.url)
+		assert.Equal(t, check.status, response.ResponseCode, check.url)
+	}
+
+	statusCode, response = doRequest(t, app, "GET", "/permission/DISTRIBUTOR1?type=json&film=F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []interface{}{"IN"}, response.Data.(map[string]interface{})["included"])
+
+	statusCode, _ = doRequest(t, app, "PUT", "/film/F1", "application/json", `{"title": "The First Film (Director's Cut)"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	statusCode, response = doRequest(t, app, "GET", "/film/F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "The First Film (Director's Cut)", response.Data.(map[string]interface{})["title"])
+	assert.Equal(t, []interface{}{"DISTRIBUTOR1", "DISTRIBUTOR2"}, response.Data.(map[string]interface{})["distributors"])
+
+	// removing a film removes every right on it
+	statusCode, response = doRequest(t, app, "DELETE", "/film/F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []interface{}{"DISTRIBUTOR1", "DISTRIBUTOR2"}, response.Data.(map[string]interface{})["revoked"])
+
+	statusCode, response = doRequest(t, app, "GET", "/film", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Empty(t, response.Data.(map[string]interface{})["films"])
+
+	statusCode, _ = doRequest(t, app, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=IN&film=F1", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"US"}, permissions.Included)
+}
+
+func TestContractVersionsPerFilm(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/film", "application/json", `{"id": "F1", "title": "The First Film"}`)
+	require.Equal(t, http.StatusCreated, statusCode)
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1 ON FILM:F1
+INCLUDE: IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "bob", `Permissions for DISTRIBUTOR1 ON FILM:F1
+INCLUDE: FR`))
+
+	statusCode, response := doRequest(t, ts.App, "GET", "/contract/DISTRIBUTOR1/versions?film=F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Len(t, response.Data.(map[string]interface{})["versions"], 2)
+	statusCode, response = doRequest(t, ts.App, "GET", "/contract/DISTRIBUTOR1/versions/2?film=F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "bob", response.Data.(map[string]interface{})["applied_by"])
+	_, response = doRequest(t, ts.App, "GET", "/contract/DISTRIBUTOR1/versions", "", "")
+	assert.Len(t, response.Data.(map[string]interface{})["versions"], 1)
+
+	// rolling back on the film leaves the rights on the whole catalog as they are
+	statusCode, response = doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR1/rollback/1?film=F1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, float64(3), response.Data.(map[string]interface{})["version"])
+	_, response = doRequest(t, ts.App, "GET", "/permission/DISTRIBUTOR1?type=json&film=F1", "", "")
+	assert.Equal(t, []interface{}{"IN"}, response.Data.(map[string]interface{})["included"])
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"US"}, permissions.Included)
+
+	statusCode, response = doRequest(t, ts.App, "GET", "/contract/DISTRIBUTOR1/versions?film=F2", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "FILM_NOT_FOUND", response.ResponseCode)
+	statusCode, _ = doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR1/rollback/1?film=F2", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistributorHierarchy(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	contracts := []struct {
+		name                 string
+		contract             string
+		expectedStatusCode   int
+		expectedResponseCode string
+	}{
+		{
+			name: "Top-level contract",
+			contract: `Permissions for DISTRIBUTOR1
+INCLUDE: IN`,
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseCode: SUCCESS,
+		},
+		{
+			name: "Sub contract",
+			contract: `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: TN-IN`,
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseCode: SUCCESS,
+		},
+		{
+			name: "Sub-sub contract with full chain",
+			contract: `Permissions for DISTRIBUTOR3 < DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: CENAI-TN-IN`,
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseCode: SUCCESS,
+		},
+		{
+			name: "Sibling sub contract",
+			contract: `Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: KA-IN`,
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseCode: SUCCESS,
+		},
+		{
+			name: "Chain not matching the recorded hierarchy",
+			contract: `Permissions for DISTRIBUTOR5 < DISTRIBUTOR3 < DISTRIBUTOR1
+INCLUDE: CENAI-TN-IN`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseCode: HIERARCHY_MISMATCH,
+		},
+		{
+			name: "Chain claiming a top-level distributor has a parent",
+			contract: `Permissions for DISTRIBUTOR5 < DISTRIBUTOR1 < DISTRIBUTOR2
+INCLUDE: TN-IN`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseCode: HIERARCHY_MISMATCH,
+		},
+		{
+			name: "Unknown distributor further up the chain",
+			contract: `Permissions for DISTRIBUTOR5 < DISTRIBUTOR2 < DISTRIBUTOR9
+INCLUDE: TN-IN`,
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseCode: PARENT_DISTRIBUTOR_NOT_FOUND,
+		},
+		{
+			name: "Re-parenting a sub-distributor",
+			contract: `Permissions for DISTRIBUTOR3 < DISTRIBUTOR4
+INCLUDE: KA-IN`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseCode: HIERARCHY_MISMATCH,
+		},
+		{
+			name: "Top-level contract for a sub-distributor",
+			contract: `Permissions for DISTRIBUTOR2
+INCLUDE: KA-IN`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseCode: HIERARCHY_MISMATCH,
+		},
+		{
+			name: "Cycle",
+			contract: `Permissions for DISTRIBUTOR1 < DISTRIBUTOR3
+INCLUDE: CENAI-TN-IN`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseCode: HIERARCHY_MISMATCH,
+		},
+		{
+			name: "Another contract with the same parent",
+			contract: `Permissions for DISTRIBUTOR3 < DISTRIBUTOR2
+INCLUDE: KLRAI-TN-IN`,
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseCode: SUCCESS,
+		},
+	}
+
+	for _, tt := range contracts {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, response := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", tt.contract)
+			assert.Equal(t, tt.expectedStatusCode, statusCode)
+			assert.Equal(t, tt.expectedResponseCode, response.ResponseCode)
+		})
+	}
+
+	lineages := map[string][]interface{}{
+		"DISTRIBUTOR1": {"DISTRIBUTOR1"},
+		"DISTRIBUTOR2": {"DISTRIBUTOR2", "DISTRIBUTOR1"},
+		"DISTRIBUTOR3": {"DISTRIBUTOR3", "DISTRIBUTOR2", "DISTRIBUTOR1"},
+		"DISTRIBUTOR4": {"DISTRIBUTOR4", "DISTRIBUTOR1"},
+	}
+	for distributor, expected := range lineages {
+		statusCode, response := doRequest(t, ts.App, "GET", "/distributor/"+distributor+"/lineage", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, expected, response.Data.(map[string]interface{})["lineage"])
+	}
+
+	children := map[string][]interface{}{
+		"DISTRIBUTOR1": {"DISTRIBUTOR2", "DISTRIBUTOR4"},
+		"DISTRIBUTOR2": {"DISTRIBUTOR3"},
+		"DISTRIBUTOR3": {},
+	}
+	for distributor, expected := range children {
+		statusCode, response := doRequest(t, ts.App, "GET", "/distributor/"+distributor+"/children", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, expected, response.Data.(map[string]interface{})["children"])
+	}
+
+	statusCode, response := doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR9/lineage", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, DISTRIBUTOR_NOT_FOUND, response.ResponseCode)
+}
+
+func TestPlacingDistributorUnderParent(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR3 < DISTRIBUTOR2
+INCLUDE: TN-IN`))
+
+	// in strict mode, a distributor can't be placed under a parent that doesn't hold what it holds
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US`)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+	violations := response.Data.(map[string]interface{})["violations"].([]interface{})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "INCLUDE: IN", violations[0].(map[string]interface{})["line"])
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"IN"}, permissions.Included)
+	_, response = doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR2/lineage", "", "")
+	assert.Equal(t, []interface{}{"DISTRIBUTOR2"}, response.Data.(map[string]interface{})["lineage"])
+
+	// in lenient mode, what it held is trimmed down to what its new parent holds, and so are its descendants, with the losses as warnings
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract?mode=lenient", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	warnings := response.Data.(map[string]interface{})["warnings"].([]interface{})
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "INCLUDE: IN", warnings[0].(map[string]interface{})["line"])
+	assert.Equal(t, "DISTRIBUTOR2 holds IN, which DISTRIBUTOR1 doesn't hold", warnings[0].(map[string]interface{})["reason"])
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"US"}, permissions.Included)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR3")
+	assert.Empty(t, permissions.Included)
+
+	statusCode, response = doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR2&region=IN", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_DENIED", response.ResponseCode)
+
+	// placing a distributor under a parent holding all it holds takes nothing away
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR4
+INCLUDE: CA-US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: TX-US`))
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR4")
+	assert.Equal(t, []string{"CA-US", "TX-US"}, permissions.Included)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// instant returns the current time as an as_of query value, making sure the writes before and after it get different timestamps.
+func instant() string {
+	time.Sleep(2 * time.Millisecond)
+	defer time.Sleep(2 * time.Millisecond)
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+func TestPointInTimePermissions(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+	app, _ := openPersistedApp(t, dir, 0)
+
+	beforeCreation := instant()
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN`))
+	beforeRevocation := instant()
+	statusCode, _ := doRequest(t, app, "POST", "/permission/disallow", "application/json", `{"distributor": "DISTRIBUTOR1", "region": "IN"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// reconstructed after a restart too
+	app, _ = openPersistedApp(t, dir, 0)
+
+	checks := []struct {
+		url        string
+		statusCode int
+		status     string
+	}{
+		{"/permission/check?distributor=DISTRIBUTOR2&region=IN", http.StatusOK, "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR2&region=IN&as_of=" + beforeRevocation, http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=KA-IN&as_of=" + beforeRevocation, http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US&as_of=" + beforeRevocation, http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&as_of=" + beforeCreation, http.StatusNotFound, "DISTRIBUTOR_NOT_FOUND"},
+	}
+	for _, check := range checks {
+		statusCode, response := doRequest(t, app, "GET", check.url, "", "")
+		assert.Equal(t, check.statusCode, statusCode, check.url)
+		assert.Equal(t, check.status, response.ResponseCode, check.url)
+	}
+
+	statusCode, _ = doRequest(t, app, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=IN&as_of=yesterday", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	statusCode, response := doRequest(t, app, "GET", "/permission/DISTRIBUTOR1?type=json&as_of="+beforeRevocation, "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.ElementsMatch(t, []interface{}{"IN", "US"}, response.Data.(map[string]interface{})["included"])
+
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"US"}, permissions.Included)
+}
+
+func TestHistoryOfRemovedDistributor(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+	app, _ := openPersistedApp(t, dir, 0)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN`))
+	beforeRemoval := instant()
+	statusCode, _ := doRequest(t, app, "DELETE", "/distributor/DISTRIBUTOR1", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	afterRemoval := instant()
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: US`))
+
+	app, _ = openPersistedApp(t, dir, 0)
+
+	checks := []struct {
+		url        string
+		statusCode int
+		status     string
+	}{
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&as_of=" + beforeRemoval, http.StatusOK, "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&as_of=" + afterRemoval, http.StatusNotFound, "DISTRIBUTOR_NOT_FOUND"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN", http.StatusOK, "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US", http.StatusOK, "FULLY_ALLOWED"},
+	}
+	for _, check := range checks {
+		statusCode, response := doRequest(t, app, "GET", check.url, "", "")
+		assert.Equal(t, check.statusCode, statusCode, check.url)
+		assert.Equal(t, check.status, response.ResponseCode, check.url)
+	}
+}
+
+func TestHistoryDoesNotGrowLogEntries(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+	app, databank := openPersistedApp(t, dir, 0)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN`))
+	for i := 0; i < 100; i++ {
+		disallowRegion(t, &TestSetup{App: app}, "DISTRIBUTOR1", "KA-IN")
+		allowRegion(t, &TestSetup{App: app}, "DISTRIBUTOR1", "KA-IN")
+	}
+	require.NoError(t, databank.Close())
+
+	// every entry carries the change it made, not the whole history
+	content, err := os.ReadFile(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	entries := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, entries, 201)
+	assert.InDelta(t, len(entries[1]), len(entries[199]), 5) //the sequence number takes more digits
+	assert.InDelta(t, len(entries[2]), len(entries[200]), 5)
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"challenge16/internal/data"
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"challenge16/internal/server"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allowRegion(t *testing.T, ts *TestSetup, distributor, region string) {
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/allow", "application/json", `{"distributor":"`+distributor+`","region":"`+region+`"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+}
+
+func disallowRegion(t *testing.T, ts *TestSetup, distributor, region string) {
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"`+distributor+`","region":"`+region+`"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+}
+
+func TestRulesAreNormalizedOnEveryChange(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/distributor", "application/json", `{"distributor":"DISTRIBUTOR1"}`)
+	require.Equal(t, http.StatusCreated, statusCode)
+
+	// every city of Puducherry, one by one
+	for _, city := range []string{"YANAM", "KARIL", "PUCER", "THIRB"} {
+		allowRegion(t, ts, "DISTRIBUTOR1", city+"-PY-IN")
+	}
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"PY-IN"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+
+	// every province of Kuwait excluded leaves nothing of it
+	allowRegion(t, ts, "DISTRIBUTOR1", "KW")
+	disallowRegion(t, ts, "DISTRIBUTOR1", "FA-KW")
+	disallowRegion(t, ts, "DISTRIBUTOR1", "MU-KW")
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"PY-IN"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+
+	// so do contracts
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: SILVA-DN-IN
+INCLUDE: AMLFU-DN-IN
+INCLUDE: DAMAN-DD-IN`))
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.ElementsMatch(t, []string{"DN-IN", "DAMAN-DD-IN"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+}
+
+func TestNormalizeStoredPermissions(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	// rules stored before normalization, written straight to the store
+	store := data.NewMemoryStore()
+	require.NoError(t, store.Put("DISTRIBUTOR1", data.Record{Permissions: dto.Permissions{
+		IncludedCountries: map[string]bool{"KW": true},
+		IncludedProvinces: map[string]map[string]bool{},
+		ExcludedProvinces: map[string]map[string]bool{"KW": {"FA": true}},
+		IncludedCities:    map[string]map[string]map[string]bool{"IN": {"DN": {"SILVA": true, "AMLFU": true}}},
+		ExcludedCities:    map[string]map[string]map[string]bool{},
+	}}))
+	app := server.NewServer(1000000000, data.NewDataBank(store))
+
+	statusCode, response := doRequest(t, app, "POST", "/permission/DISTRIBUTOR1/normalize", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, map[string]interface{}{
+		"distributor": "DISTRIBUTOR1",
+		"included":    []interface{}{"DN-IN", "MU-KW"},
+		"excluded":    []interface{}{},
+		"changed":     true,
+	}, response.Data)
+
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"DN-IN", "MU-KW"}, permissions.Included)
+
+	// already minimal
+	statusCode, response = doRequest(t, app, "POST", "/permission/DISTRIBUTOR1/normalize", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, false, response.Data.(map[string]interface{})["changed"])
+
+	statusCode, _ = doRequest(t, app, "POST", "/permission/DISTRIBUTOR9/normalize", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractExceedingParentPermissions(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`)
+	require.Equal(t, http.StatusOK, statusCode)
+
+	contract := `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: CN
+INCLUDE: TN-IN
+INCLUDE: YADGR-KA-IN`
+
+	// strict by default
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"line":   "INCLUDE: CN",
+			"reason": "DISTRIBUTOR1 doesn't hold CN",
+		},
+		map[string]interface{}{
+			"line":       "INCLUDE: YADGR-KA-IN",
+			"reason":     "DISTRIBUTOR1 doesn't hold YADGR-KA-IN, it has EXCLUDE: KA-IN",
+			"blocked_by": map[string]interface{}{"rule": "EXCLUDE: KA-IN", "level": "province"},
+		},
+	}, response.Data.(map[string]interface{})["violations"])
+
+	statusCode, _ = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	// including a region the parent holds only in part is fine: its exclusions are inherited
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract?mode=strict", "text/plain", `Permissions for DISTRIBUTOR3 < DISTRIBUTOR1
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Empty(t, response.Data.(map[string]interface{})["warnings"])
+
+	// lenient mode trims the violations, and reports them
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract?mode=lenient", "text/plain", contract)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Len(t, response.Data.(map[string]interface{})["warnings"], 2)
+
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.ElementsMatch(t, []string{"TN-IN"}, permissions.Included)
+
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/contract?mode=loose", "text/plain", contract)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
+
+func TestInclusionExceedingParentPermissions(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: US
+INCLUDE: IN
+EXCLUDE: KA-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR3 < DISTRIBUTOR1
+INCLUDE: TN-IN`))
+
+	// strict by default
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/allow", "application/json", `{"distributor":"DISTRIBUTOR3","region":"FR"}`)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"line":   "INCLUDE: FR",
+			"reason": "DISTRIBUTOR1 doesn't hold FR",
+		},
+	}, response.Data.(map[string]interface{})["violations"])
+	assert.Equal(t, "Permissions for DISTRIBUTOR3 < DISTRIBUTOR1\nINCLUDE: TN-IN", getPermissionsText(t, ts.App, "DISTRIBUTOR3"))
+
+	// a region the parent holds in part is trimmed down to what it holds
+	allowRegion(t, ts, "DISTRIBUTOR3", "IN")
+	statusCode, response = doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR3&region=YADGR-KA-IN", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_DENIED", response.ResponseCode)
+
+	// lenient mode trims the regions the parent doesn't hold, and reports them
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/allow?mode=lenient", "application/json", `{"distributor":"DISTRIBUTOR3","region":"FR"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Len(t, response.Data.(map[string]interface{})["warnings"], 1)
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR3")
+	assert.Equal(t, []string{"IN"}, permissions.Included)
+	assert.Equal(t, []string{"KA-IN"}, permissions.Excluded)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"challenge16/internal/permissions"
+	"challenge16/internal/regions"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// permissionSet builds a set from INCLUDE/EXCLUDE lines, applied in order.
+func permissionSet(t *testing.T, lines ...string) permissions.PermissionSet {
+	set := permissions.New()
+	for _, line := range lines {
+		region, err := regions.GetRegionDetails(line[1:])
+		require.NoError(t, err)
+		if line[0] == '+' {
+			set = set.Include(region)
+		} else {
+			set = set.Exclude(region)
+		}
+	}
+	return set
+}
+
+func sortedRegionLists(set permissions.PermissionSet) ([]string, []string) {
+	included, excluded := set.RegionLists()
+	sort.Strings(included)
+	sort.Strings(excluded)
+	return included, excluded
+}
+
+func TestPermissionSetAlgebra(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	india := permissionSet(t, "+IN", "-KA-IN")
+	south := permissionSet(t, "+KA-IN", "+TN-IN", "+US")
+
+	included, excluded := sortedRegionLists(india.Union(south))
+	assert.Equal(t, []string{"IN", "US"}, included)
+	assert.Empty(t, excluded)
+
+	included, excluded = sortedRegionLists(india.Intersect(south))
+	assert.Equal(t, []string{"TN-IN"}, included)
+	assert.Empty(t, excluded)
+
+	included, excluded = sortedRegionLists(india.Subtract(south))
+	assert.Equal(t, []string{"IN"}, included)
+	assert.Equal(t, []string{"KA-IN", "TN-IN"}, excluded)
+
+	// the same regions, marked differently
+	assert.True(t, permissionSet(t, "+IN", "-KA-IN").Equal(permissionSet(t, "+IN").Subtract(permissionSet(t, "+KA-IN"))))
+	assert.False(t, india.Equal(south))
+
+	assert.True(t, permissionSet(t, "+TN-IN").IsSubsetOf(india))
+	assert.False(t, south.IsSubsetOf(india))
+	assert.True(t, permissions.New().IsSubsetOf(india))
+	assert.True(t, india.Intersect(permissionSet(t, "+US")).IsEmpty())
+
+	// the operands are left as they were
+	included, excluded = sortedRegionLists(india)
+	assert.Equal(t, []string{"IN"}, included)
+	assert.Equal(t, []string{"KA-IN"}, excluded)
+}
+
+func TestPermissionSetNormalize(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	// Chandigarh is the only city of its province
+	included, excluded := sortedRegionLists(permissionSet(t, "+CHNAR-CH-IN").Normalize())
+	assert.Equal(t, []string{"CH-IN"}, included)
+	assert.Empty(t, excluded)
+
+	// every province of the country but one
+	allButKarnataka := permissions.New()
+	for provinceCode := range regions.Countries()["IN"].Provinces {
+		if provinceCode != "KA" {
+			region, err := regions.GetRegionDetails(provinceCode + "-IN")
+			require.NoError(t, err)
+			allButKarnataka = allButKarnataka.Include(region)
+		}
+	}
+	normalized := allButKarnataka.Normalize()
+	included, excluded = sortedRegionLists(normalized)
+	assert.Equal(t, []string{"IN"}, included)
+	assert.Equal(t, []string{"KA-IN"}, excluded)
+	// they cover the same cities, although not the same regions: IN itself is only partially covered by the provinces
+	assert.True(t, normalized.Subtract(allButKarnataka).Normalize().IsEmpty())
+	assert.True(t, allButKarnataka.IsSubsetOf(normalized))
+	assert.False(t, normalized.Equal(allButKarnataka))
+}
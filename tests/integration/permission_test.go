@@ -19,6 +19,7 @@ func TestDistributorPermissionsFlow(t *testing.T) {
 
 	tests := []struct {
 		name                 string
+		query                string
 		contract             string
 		expectedStatusCode   int
 		expectedStatus       bool
@@ -100,7 +101,22 @@ EXCLUDE: KA-IN`,
 			expectedExcluded:          []string{"KA-IN", "CENAI-TN-IN"},
 		},
 		{
-			name: "Sub contract with extra inclusion and exclusion",
+			name: "Sub contract including a region the parent doesn't hold",
+			contract: `Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: PA`,
+			expectedStatusCode:   http.StatusConflict,
+			expectedStatus:       false,
+			expectedResponseCode: "EXCEEDS_PARENT_PERMISSIONS",
+
+			recipientDistributor:      "DISTRIBUTOR4",
+			expectedStatusCodeInGet:   http.StatusNotFound,
+			expectedStatusInGet:       false,
+			expectedResponseCodeInGet: DISTRIBUTOR_NOT_FOUND,
+		},
+		{
+			name:  "Sub contract with extra inclusion and exclusion, in lenient mode",
+			query: "?mode=lenient",
 			contract: `Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
 INCLUDE: IN
 INCLUDE: US
@@ -141,23 +157,24 @@ EXCLUDE: KA-IN`,
 			contract: `Permissions for DISTRIBUTOR5 < DISTRIBUTOR1
 INCLUDE: IN
 EXCLUDE: AP-IN`,
-			expectedStatusCode:   http.StatusOK,
-			expectedStatus:       true,
-			expectedResponseCode: SUCCESS,
+			//DISTRIBUTOR5 holds PA and CENAI-TN-IN, which DISTRIBUTOR1 doesn't hold, so it can't be placed under DISTRIBUTOR1 in strict mode
+			expectedStatusCode:   http.StatusConflict,
+			expectedStatus:       false,
+			expectedResponseCode: "EXCEEDS_PARENT_PERMISSIONS",
 
 			recipientDistributor:      "DISTRIBUTOR5",
 			expectedStatusCodeInGet:   http.StatusOK,
 			expectedStatusInGet:       true,
 			expectedResponseCodeInGet: SUCCESS,
 			expectedIncluded:          []string{"IN", "PA"},
-			expectedExcluded:          []string{"KA-IN"}, //CENA-TN-IN is not excluded because it is already permitted for DISTRIBUTOR5, so exclusion in contract is ignored
+			expectedExcluded:          []string{"KA-IN"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// POST request to create a contract
-			req := httptest.NewRequest("POST", "/permission/contract", strings.NewReader(tt.contract))
+			req := httptest.NewRequest("POST", "/permission/contract"+tt.query, strings.NewReader(tt.contract))
 			req.Header.Set("Content-Type", "text/plain")
 
 			resp, err := ts.App.Test(req)
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"challenge16/internal/data"
+	"challenge16/internal/regions"
+	"challenge16/internal/server"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openPersistedApp(t *testing.T, dir string, snapshotInterval int) (*fiber.App, *data.DataBank) {
+	store, err := data.OpenFileStore(dir, snapshotInterval)
+	require.NoError(t, err)
+	databank := data.NewDataBank(store)
+	return server.NewServer(1000000000, databank), databank
+}
+
+func TestDataBankSurvivesRestart(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+
+	app, databank := openPersistedApp(t, dir, 0)
+
+	statusCode, _ := doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US
+EXCLUDE: KA-IN`)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = doRequest(t, app, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"US"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	statusCode, _ = doRequest(t, app, "POST", "/distributor", "application/json", `{"distributor":"DISTRIBUTOR3"}`)
+	assert.Equal(t, http.StatusCreated, statusCode)
+	statusCode, _ = doRequest(t, app, "DELETE", "/distributor/DISTRIBUTOR3", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	require.NoError(t, databank.Close())
+
+	// simulate a crash in the middle of appending an entry
+	wal, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = wal.WriteString(`1234abcd {"seq":99,"changes":{"DISTRIB`)
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	app, databank = openPersistedApp(t, dir, 0)
+	defer databank.Close()
+
+	statusCode, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	assert.ElementsMatch(t, []string{"KA-IN"}, permissions.Excluded)
+
+	statusCode, permissions = getPermissions(t, app, "DISTRIBUTOR2")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	assert.ElementsMatch(t, []string{"KA-IN"}, permissions.Excluded)
+
+	statusCode, _ = getPermissions(t, app, "DISTRIBUTOR3")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	_, response := doRequest(t, app, "GET", "/distributor/DISTRIBUTOR2/lineage", "", "")
+	assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR1"}, response.Data.(map[string]interface{})["lineage"])
+
+	// the torn entry is discarded, so new entries are appended after the last complete one
+	statusCode, _ = doRequest(t, app, "POST", "/distributor", "application/json", `{"distributor":"DISTRIBUTOR4"}`)
+	assert.Equal(t, http.StatusCreated, statusCode)
+}
+
+func TestDataBankSnapshotCompaction(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+
+	app, databank := openPersistedApp(t, dir, 2)
+	for _, distributor := range []string{"DISTRIBUTOR1", "DISTRIBUTOR2", "DISTRIBUTOR3"} {
+		statusCode, _ := doRequest(t, app, "POST", "/distributor", "application/json", `{"distributor":"`+distributor+`"}`)
+		assert.Equal(t, http.StatusCreated, statusCode)
+	}
+	statusCode, _ := doRequest(t, app, "POST", "/permission/allow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"TN-IN"}`)
+	assert.Equal(t, http.StatusOK, statusCode)
+	require.NoError(t, databank.Close())
+
+	_, err := os.Stat(filepath.Join(dir, "snapshot.json"))
+	assert.NoError(t, err)
+	walInfo, err := os.Stat(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Zero(t, walInfo.Size(), "log should be emptied once compacted")
+
+	app, databank = openPersistedApp(t, dir, 2)
+	defer databank.Close()
+
+	_, response := doRequest(t, app, "GET", "/distributor", "", "")
+	assert.ElementsMatch(t, []interface{}{"DISTRIBUTOR1", "DISTRIBUTOR2", "DISTRIBUTOR3"}, response.Data.(map[string]interface{})["distributors"])
+
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"TN-IN"}, permissions.Included)
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionDistributors(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	for _, contract := range []string{
+		`Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: CENAI-TN-IN`,
+		`Permissions for DISTRIBUTOR2
+INCLUDE: TN-IN`,
+		`Permissions for DISTRIBUTOR3
+INCLUDE: US`,
+		`Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: KA-IN`,
+	} {
+		statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+		require.Equal(t, http.StatusOK, statusCode)
+	}
+
+	statusCode, response := doRequest(t, ts.App, "GET", "/region/CENAI-TN-IN/distributors", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"distributor": "DISTRIBUTOR2", "status": "FULLY_ALLOWED"},
+	}, response.Data.(map[string]interface{})["distributors"])
+
+	_, response = doRequest(t, ts.App, "GET", "/region/TN-IN/distributors", "", "")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"distributor": "DISTRIBUTOR1", "status": "PARTIALLY_ALLOWED"},
+		map[string]interface{}{"distributor": "DISTRIBUTOR2", "status": "FULLY_ALLOWED"},
+	}, response.Data.(map[string]interface{})["distributors"])
+
+	_, response = doRequest(t, ts.App, "GET", "/region/IN/distributors", "", "")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"distributor": "DISTRIBUTOR1", "status": "PARTIALLY_ALLOWED"},
+		map[string]interface{}{"distributor": "DISTRIBUTOR2", "status": "PARTIALLY_ALLOWED"},
+		map[string]interface{}{"distributor": "DISTRIBUTOR4", "status": "PARTIALLY_ALLOWED"},
+	}, response.Data.(map[string]interface{})["distributors"])
+
+	// the index follows revocations, cascades and removals
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"KA-IN"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR2", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	_, response = doRequest(t, ts.App, "GET", "/region/KA-IN/distributors", "", "")
+	assert.Empty(t, response.Data.(map[string]interface{})["distributors"])
+
+	_, response = doRequest(t, ts.App, "GET", "/region/TN-IN/distributors", "", "")
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"distributor": "DISTRIBUTOR1", "status": "PARTIALLY_ALLOWED"},
+	}, response.Data.(map[string]interface{})["distributors"])
+
+	statusCode, _ = doRequest(t, ts.App, "GET", "/region/XX/distributors", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRegionNames(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	for name, code := range map[string]string{
+		"INDIA":                         "IN",
+		"united  states":                "US",
+		"KARNATAKA-INDIA":               "KA-IN",
+		"TAMILNADU-INDIA":               "TN-IN",
+		"Yellapur-Karnataka-India":      "YELUR-KA-IN",
+		"YELLAPUR-KA-IN":                "YELUR-KA-IN", // names and codes mixed
+		"dehri-on-sone-BIHAR-INDIA":     "DRIOS-BR-IN", // a name with hyphens
+		"KA-IN":                         "KA-IN",
+		"ka-in":                         "KA-IN",
+		"Athens - Ohio - United States": "ATHEN-OH-US",
+	} {
+		resolved, err := regions.Resolve(name)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, code, resolved, name)
+		}
+	}
+
+	// an unknown region is left to GetRegionDetails
+	resolved, err := regions.Resolve("ATLANTIS-INDIA")
+	require.Error(t, err)
+	assert.Equal(t, "ATLANTIS-INDIA", resolved)
+	assert.True(t, strings.HasPrefix(err.Error(), regions.InvalidRegionPrefix))
+
+	// two cities of Ohio are named Valley View and Valleyview
+	_, err = regions.Resolve("VALLEY VIEW-OHIO-UNITED STATES")
+	var ambiguous *regions.AmbiguousRegionError
+	require.ErrorAs(t, err, &ambiguous)
+	assert.Equal(t, []regions.Candidate{
+		{Code: "VALYI-OH-US", Name: "Valleyview, Ohio, United States"},
+		{Code: "VVIEW-OH-US", Name: "Valley View, Ohio, United States"},
+	}, ambiguous.Candidates)
+}
+
+func TestRegionNamesInContractsAndChecks(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: INDIA
+INCLUDE: UNITED STATES
+EXCLUDE: KARNATAKA-INDIA
+EXCLUDE: Tamil Nadu-India`))
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"IN", "US"}, permissions.Included)
+	assert.Equal(t, []string{"KA-IN", "TN-IN"}, permissions.Excluded)
+
+	check := func(region string) string {
+		statusCode, response := doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR1&region="+url.QueryEscape(region), "", "")
+		require.Equal(t, http.StatusOK, statusCode, region)
+		return response.ResponseCode
+	}
+	assert.Equal(t, "FULLY_DENIED", check("yellapur-karnataka-india"))
+	assert.Equal(t, "FULLY_ALLOWED", check("Athens-Ohio-United States"))
+
+	statusCode, _ := doRequest(t, ts.App, "POST", "/permission/allow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"Yellapur-Karnataka-India"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"Athens-Ohio-United States"}`)
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_ALLOWED", check("YELUR-KA-IN"))
+	assert.Equal(t, "FULLY_DENIED", check("ATHEN-OH-US"))
+
+	// ambiguous names are rejected with their candidates
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/allow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"Valley View-Ohio-United States"}`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "AMBIGUOUS_REGION", response.ResponseCode)
+	assert.Contains(t, response.Error, "VALYI-OH-US (Valleyview, Ohio, United States), VVIEW-OH-US (Valley View, Ohio, United States)")
+	assert.Len(t, response.Data.(map[string]interface{})["candidates"], 2)
+
+	statusCode, response = doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: VALLEY VIEW-OHIO-UNITED STATES`)
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "INVALID_CONTRACT", response.ResponseCode)
+	errs := response.Data.(map[string]interface{})["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Len(t, errs[0].(map[string]interface{})["candidates"], 2)
+
+	// unknown names are still not found
+	statusCode, response = doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=ATLANTIS-INDIA", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "REGION_NOT_FOUND", response.ResponseCode)
+}
+
+func TestRegionNamesInBatchChecksAndLookups(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`))
+
+	// an ambiguous name fails its own check only
+	statusCode, response := doRequest(t, ts.App, "POST", "/permission/check/batch", "application/json", `{"distributor":"DISTRIBUTOR1","regions":["Tamil Nadu-India","KARNATAKA-INDIA","Valley View-Ohio-United States","ATLANTIS-INDIA"]}`)
+	require.Equal(t, http.StatusOK, statusCode)
+	results := response.Data.(map[string]interface{})["results"].([]interface{})
+	require.Len(t, results, 4)
+	assert.Equal(t, "FULLY_ALLOWED", results[0].(map[string]interface{})["status"])
+	assert.Equal(t, "FULLY_DENIED", results[1].(map[string]interface{})["status"])
+	assert.Equal(t, "AMBIGUOUS_REGION", results[2].(map[string]interface{})["error_code"])
+	assert.Len(t, results[2].(map[string]interface{})["candidates"], 2)
+	assert.Equal(t, "REGION_NOT_FOUND", results[3].(map[string]interface{})["error_code"])
+
+	statusCode, explanation := explainPermission(t, ts, "DISTRIBUTOR1", url.QueryEscape("Yellapur-Karnataka-India"))
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "YELUR-KA-IN", explanation.Region)
+	assert.Equal(t, "FULLY_DENIED", explanation.Decision)
+	statusCode, _ = explainPermission(t, ts, "DISTRIBUTOR1", url.QueryEscape("Valley View-Ohio-United States"))
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	statusCode, response = doRequest(t, ts.App, "GET", "/region/"+url.PathEscape("Tamil Nadu-India")+"/distributors", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"distributor": "DISTRIBUTOR1", "status": "FULLY_ALLOWED"},
+	}, response.Data.(map[string]interface{})["distributors"])
+	statusCode, response = doRequest(t, ts.App, "GET", "/region/"+url.PathEscape("Valley View-Ohio-United States")+"/distributors", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "AMBIGUOUS_REGION", response.ResponseCode)
+}
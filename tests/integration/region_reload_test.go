@@ -0,0 +1,199 @@
+package test
+
+import (
+	"challenge16/internal/data"
+	"challenge16/internal/dto"
+	"challenge16/internal/regions"
+	"challenge16/internal/server"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCatalog writes the rows of cities.csv that keep accepts (given the city, province and country codes) to path.
+func writeCatalog(t *testing.T, path string, keep func(city, province, country string) bool) {
+	content, err := os.ReadFile(csvFile)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	kept := []string{lines[0]}
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		if keep(fields[0], fields[1], fields[2]) {
+			kept = append(kept, line)
+		}
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), 0o644))
+}
+
+// useCatalogCopy loads a copy of cities.csv as the region catalog, for the test to change and reload, and restores cities.csv afterwards.
+func useCatalogCopy(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "cities.csv")
+	writeCatalog(t, path, func(city, province, country string) bool { return true })
+	require.NoError(t, regions.LoadDataIntoMap(path))
+	t.Cleanup(func() {
+		require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	})
+	return path
+}
+
+func TestReloadRegions(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	path := useCatalogCopy(t)
+	app := server.NewServer(1000000000, data.NewDataBank(data.NewMemoryStore()))
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: KW
+INCLUDE: PY-IN`))
+
+	// Karnataka and Kuwait are gone
+	writeCatalog(t, path, func(city, province, country string) bool {
+		return country != "KW" && !(country == "IN" && province == "KA")
+	})
+	statusCode, response := doRequest(t, app, "POST", "/admin/regions/reload", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	reload := decodeData[dto.RegionReloadData](t, response.Data)
+	assert.Equal(t, path, reload.Source)
+	assert.Equal(t, []dto.VanishedRegion{
+		{Distributor: "DISTRIBUTOR1", Region: "KA-IN", Rule: "EXCLUDE"},
+		{Distributor: "DISTRIBUTOR2", Region: "KW", Rule: "INCLUDE"},
+	}, reload.Vanished)
+	countries, provinces, cities := 0, 0, 0
+	for _, country := range regions.Countries() {
+		countries++
+		for _, province := range country.Provinces {
+			provinces++
+			cities += len(province.Cities)
+		}
+	}
+	assert.Equal(t, []int{countries, provinces, cities}, []int{reload.Countries, reload.Provinces, reload.Cities})
+
+	assert.False(t, regions.CheckCountry("KW"))
+	assert.False(t, regions.CheckProvince("IN", "KA"))
+	statusCode, response = doRequest(t, app, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=YELUR-KA-IN", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "REGION_NOT_FOUND", response.ResponseCode)
+	assert.NotContains(t, codes(regions.Search("yellap", "", "", 20)), "YELUR-KA-IN")
+
+	// a file that doesn't validate leaves the catalog as it is
+	require.NoError(t, os.WriteFile(path, []byte("City Code,Province Code,Country Code,City Name,Province Name,Country Name\nA-B,KA,IN,Ab,Karnataka,India\n,KA,IN,Nowhere,Karnataka,India\n"), 0o644))
+	statusCode, response = doRequest(t, app, "POST", "/admin/regions/reload", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.Equal(t, "INVALID_REGION_CATALOG", response.ResponseCode)
+	assert.Contains(t, response.Error, `row 2: invalid code "A-B"; row 3: no city code`)
+	assert.True(t, regions.CheckCountry("IN"))
+	assert.False(t, regions.CheckCountry("KW"))
+
+	require.NoError(t, os.WriteFile(path, []byte("City\n"), 0o644))
+	statusCode, _ = doRequest(t, app, "POST", "/admin/regions/reload", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	assert.True(t, regions.CheckCountry("IN"))
+}
+
+func TestRulesOnVanishedRegionsOutliveChanges(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+	path := useCatalogCopy(t)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+EXCLUDE: KA-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: KW`))
+
+	writeCatalog(t, path, func(city, province, country string) bool {
+		return country != "KW" && !(country == "IN" && province == "KA")
+	})
+	statusCode, _ := doRequest(t, ts.App, "POST", "/admin/regions/reload", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+
+	// the distributors' rules change while Karnataka and Kuwait are gone
+	allowRegion(t, ts, "DISTRIBUTOR1", "US")
+	allowRegion(t, ts, "DISTRIBUTOR2", "FR")
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"IN", "US"}, permissions.Included)
+	assert.Equal(t, []string{"KA-IN"}, permissions.Excluded)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"FR", "KW"}, permissions.Included)
+
+	// and the rules take effect again when they're back
+	writeCatalog(t, path, func(city, province, country string) bool { return true })
+	statusCode, _ = doRequest(t, ts.App, "POST", "/admin/regions/reload", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	statusCode, response := doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR2&region=KW", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "FULLY_ALLOWED", response.ResponseCode)
+	_, response = doRequest(t, ts.App, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=YELUR-KA-IN", "", "")
+	assert.Equal(t, "FULLY_DENIED", response.ResponseCode)
+
+	// an exclusion on a vanished region goes along with the inclusion it was carved out of
+	writeCatalog(t, path, func(city, province, country string) bool { return !(country == "IN" && province == "KA") })
+	statusCode, _ = doRequest(t, ts.App, "POST", "/admin/regions/reload", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	disallowRegion(t, ts, "DISTRIBUTOR1", "IN")
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"US"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+}
+
+func TestRegionLookupsDuringReloads(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	path := useCatalogCopy(t)
+	databank := data.NewDataBank(data.NewMemoryStore())
+
+	// India is in every catalog swapped in, so it's always found
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, err := regions.GetRegionDetails("PUCER-PY-IN")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		withKuwait := i == 1
+		writeCatalog(t, path, func(city, province, country string) bool { return withKuwait || country != "KW" })
+		resp := databank.ReloadRegions()
+		require.NoError(t, resp.Error)
+		assert.Equal(t, withKuwait, regions.CheckCountry("KW"))
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestRegionWatcher(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	path := useCatalogCopy(t)
+	databank := data.NewDataBank(data.NewMemoryStore())
+
+	stop := databank.StartRegionWatcher(10 * time.Millisecond)
+	defer stop()
+
+	writeCatalog(t, path, func(city, province, country string) bool { return country != "KW" })
+	later := time.Now().Add(time.Minute) //the modification time must differ, whatever the resolution of the file system
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	assert.Eventually(t, func() bool { return !regions.CheckCountry("KW") }, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, regions.CheckCountry("IN"))
+}
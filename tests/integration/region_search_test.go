@@ -0,0 +1,80 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchRegions(t *testing.T, ts *TestSetup, query string) (int, []regions.SearchHit) {
+	statusCode, response := doRequest(t, ts.App, "GET", "/regions/search?"+query, "", "")
+
+	data := decodeData[struct {
+		Regions []regions.SearchHit `json:"regions"`
+	}](t, response.Data)
+	return statusCode, data.Regions
+}
+
+func codes(hits []regions.SearchHit) []string {
+	found := make([]string, len(hits))
+	for i, hit := range hits {
+		found[i] = hit.Code
+	}
+	return found
+}
+
+func TestSearchRegions(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	// prefix of a name
+	statusCode, hits := searchRegions(t, ts, "q=yellap&type=city&country=IN")
+	require.Equal(t, http.StatusOK, statusCode)
+	require.NotEmpty(t, hits)
+	assert.Equal(t, regions.SearchHit{Code: "YELUR-KA-IN", Name: "Yellapur, Karnataka, India", Type: "city", Match: "prefix"}, hits[0])
+
+	// prefix of a word of the name, and of a code
+	_, hits = searchRegions(t, ts, "q=nadu&type=province&country=IN")
+	assert.Contains(t, codes(hits), "TN-IN")
+	_, hits = searchRegions(t, ts, "q=yelu&type=city")
+	assert.Subset(t, codes(hits)[:2], []string{"YELUR-KA-IN", "YELUA-KA-IN"})
+	_, hits = searchRegions(t, ts, "q=yelur")
+	require.NotEmpty(t, hits)
+	assert.Equal(t, regions.SearchHit{Code: "YELUR-KA-IN", Name: "Yellapur, Karnataka, India", Type: "city", Match: "exact"}, hits[0])
+
+	// an exact match comes first
+	_, hits = searchRegions(t, ts, "q=India")
+	require.NotEmpty(t, hits)
+	assert.Equal(t, regions.SearchHit{Code: "IN", Name: "India", Type: "country", Match: "exact"}, hits[0])
+
+	// typos
+	_, hits = searchRegions(t, ts, "q=karnatka&type=province")
+	require.NotEmpty(t, hits)
+	assert.Equal(t, regions.SearchHit{Code: "KA-IN", Name: "Karnataka, India", Type: "province", Match: "fuzzy", Distance: 1}, hits[0])
+	_, hits = searchRegions(t, ts, "q=yelapur&country=IN")
+	assert.Contains(t, codes(hits), "YELUR-KA-IN")
+	_, hits = searchRegions(t, ts, "q=hubli&type=city&country=IN") //Hugli is an edit away, Hubballi two
+	assert.Contains(t, hits, regions.SearchHit{Code: "HBALI-KA-IN", Name: "Hubballi, Karnataka, India", Type: "city", Match: "fuzzy", Distance: 2})
+
+	// filters and limit
+	_, hits = searchRegions(t, ts, "q=a&limit=5")
+	assert.Len(t, hits, 5)
+	_, hits = searchRegions(t, ts, "q=a&type=country&country=US")
+	assert.Empty(t, hits)
+	_, hits = searchRegions(t, ts, "q=yella&country=US")
+	for _, hit := range hits {
+		assert.Regexp(t, "US$", hit.Code)
+	}
+
+	statusCode, _ = doRequest(t, ts.App, "GET", "/regions/search", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "GET", "/regions/search?q=a&type=planet", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "GET", "/regions/search?q=a&country=XX", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+	statusCode, _ = doRequest(t, ts.App, "GET", "/regions/search?q=a&limit=1000", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
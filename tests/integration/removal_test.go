@@ -0,0 +1,124 @@
+package test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRemovalHierarchy(t *testing.T, ts *TestSetup) {
+	for _, contract := range []string{
+		`Permissions for DISTRIBUTOR1
+INCLUDE: IN`,
+		`Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: TN-IN`,
+		`Permissions for DISTRIBUTOR3 < DISTRIBUTOR2
+INCLUDE: CENAI-TN-IN`,
+		`Permissions for DISTRIBUTOR4 < DISTRIBUTOR1
+INCLUDE: KA-IN`,
+	} {
+		statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+		assert.Equal(t, http.StatusOK, statusCode)
+	}
+}
+
+func TestRemoveDistributorPolicies(t *testing.T) {
+	t.Run("restrict", func(t *testing.T) {
+		ts := SetupIntegrationTest(t)
+		defer CleanupTest(t, ts)
+		setupRemovalHierarchy(t, ts)
+
+		for _, url := range []string{"/distributor/DISTRIBUTOR1", "/distributor/DISTRIBUTOR1?policy=restrict"} {
+			statusCode, response := doRequest(t, ts.App, "DELETE", url, "", "")
+			assert.Equal(t, http.StatusConflict, statusCode)
+			assert.Equal(t, "DISTRIBUTOR_HAS_CHILDREN", response.ResponseCode)
+			assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR4"}, response.Data.(map[string]interface{})["children"])
+		}
+
+		// a distributor without sub-distributors can be removed
+		statusCode, response := doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR3", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []interface{}{"DISTRIBUTOR3"}, response.Data.(map[string]interface{})["removed"])
+
+		_, response = doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR2/children", "", "")
+		assert.Empty(t, response.Data.(map[string]interface{})["children"])
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		ts := SetupIntegrationTest(t)
+		defer CleanupTest(t, ts)
+		setupRemovalHierarchy(t, ts)
+
+		statusCode, response := doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR2?policy=cascade", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR3"}, response.Data.(map[string]interface{})["removed"])
+
+		_, response = doRequest(t, ts.App, "GET", "/distributor", "", "")
+		assert.ElementsMatch(t, []interface{}{"DISTRIBUTOR1", "DISTRIBUTOR4"}, response.Data.(map[string]interface{})["distributors"])
+	})
+
+	t.Run("cascade on a film", func(t *testing.T) {
+		ts := SetupIntegrationTest(t)
+		defer CleanupTest(t, ts)
+		setupRemovalHierarchy(t, ts)
+
+		statusCode, _ := doRequest(t, ts.App, "POST", "/film", "application/json", `{"id": "F1", "title": "The First Film"}`)
+		assert.Equal(t, http.StatusCreated, statusCode)
+		for _, contract := range []string{
+			`Permissions for DISTRIBUTOR5
+INCLUDE: US`,
+			`Permissions for DISTRIBUTOR2 ON FILM:F1
+INCLUDE: IN`,
+			`Permissions for DISTRIBUTOR3 < DISTRIBUTOR2 ON FILM:F1
+INCLUDE: TN-IN`,
+			`Permissions for DISTRIBUTOR5 < DISTRIBUTOR2 ON FILM:F1
+INCLUDE: KA-IN`,
+		} {
+			assert.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", contract))
+		}
+
+		// DISTRIBUTOR5 is only under DISTRIBUTOR2 on the film, so it only loses its rights on the film
+		statusCode, response := doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR2?policy=cascade", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR3"}, response.Data.(map[string]interface{})["removed"])
+		assert.Equal(t, map[string]interface{}{"F1": []interface{}{"DISTRIBUTOR5"}}, response.Data.(map[string]interface{})["revoked_on_films"])
+
+		_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR5")
+		assert.Equal(t, []string{"US"}, permissions.Included)
+		_, response = doRequest(t, ts.App, "GET", "/permission/DISTRIBUTOR5?type=json&film=F1", "", "")
+		assert.Empty(t, response.Data.(map[string]interface{})["included"])
+	})
+
+	t.Run("orphan-revoke", func(t *testing.T) {
+		ts := SetupIntegrationTest(t)
+		defer CleanupTest(t, ts)
+		setupRemovalHierarchy(t, ts)
+
+		statusCode, response := doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR1?policy=orphan-revoke", "", "")
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, []interface{}{"DISTRIBUTOR1"}, response.Data.(map[string]interface{})["removed"])
+		assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR3", "DISTRIBUTOR4"}, response.Data.(map[string]interface{})["revoked"])
+
+		for _, distributor := range []string{"DISTRIBUTOR2", "DISTRIBUTOR3", "DISTRIBUTOR4"} {
+			statusCode, permissions := getPermissions(t, ts.App, distributor)
+			assert.Equal(t, http.StatusOK, statusCode)
+			assert.Empty(t, permissions.Included)
+			assert.Empty(t, permissions.Excluded)
+		}
+
+		// the children become top-level distributors, deeper descendants keep their parent
+		_, response = doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR3/lineage", "", "")
+		assert.Equal(t, []interface{}{"DISTRIBUTOR3", "DISTRIBUTOR2"}, response.Data.(map[string]interface{})["lineage"])
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		ts := SetupIntegrationTest(t)
+		defer CleanupTest(t, ts)
+		setupRemovalHierarchy(t, ts)
+
+		statusCode, response := doRequest(t, ts.App, "DELETE", "/distributor/DISTRIBUTOR1?policy=everything", "", "")
+		assert.Equal(t, http.StatusBadRequest, statusCode)
+		assert.Equal(t, "VALIDATION_ERROR", response.ResponseCode)
+	})
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getPermissionsText(t *testing.T, app *fiber.App, distributor string) string {
+	resp, err := app.Test(httptest.NewRequest("GET", "/permission/"+distributor, nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestPermissionOutputIsSorted(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: US
+INCLUDE: ONATI-SS-ES
+INCLUDE: IN
+INCLUDE: FA-KW
+EXCLUDE: PUNCH-JK-IN
+EXCLUDE: CA-US
+EXCLUDE: AL-US
+EXCLUDE: TN-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: FR`))
+
+	// countries, then provinces, then cities, alphabetical within, on every call
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, `Permissions for DISTRIBUTOR2
+INCLUDE: IN
+INCLUDE: US
+INCLUDE: FA-KW
+INCLUDE: ONATI-SS-ES
+EXCLUDE: TN-IN
+EXCLUDE: AL-US
+EXCLUDE: CA-US
+EXCLUDE: PUNCH-JK-IN`, getPermissionsText(t, ts.App, "DISTRIBUTOR2"))
+
+		_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR2")
+		assert.Equal(t, []string{"IN", "US", "FA-KW", "ONATI-SS-ES"}, permissions.Included)
+		assert.Equal(t, []string{"TN-IN", "AL-US", "CA-US", "PUNCH-JK-IN"}, permissions.Excluded)
+
+		_, response := doRequest(t, ts.App, "GET", "/distributor", "", "")
+		assert.Equal(t, []interface{}{"DISTRIBUTOR1", "DISTRIBUTOR2"}, response.Data.(map[string]interface{})["distributors"])
+	}
+}
+
+func TestRegionListingsAreSorted(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	listing := func(url, key, field string) []string {
+		statusCode, response := doRequest(t, ts.App, "GET", url, "", "")
+		require.Equal(t, http.StatusOK, statusCode)
+		values := []string{}
+		for _, region := range response.Data.(map[string]interface{})[key].([]interface{}) {
+			values = append(values, region.(map[string]interface{})[field].(string))
+		}
+		return values
+	}
+
+	for _, tc := range []struct {
+		url, key string
+	}{
+		{"/regions/countries", "countries"},
+		{"/regions/provinces/IN", "provinces"},
+		{"/regions/cities/IN/TN", "cities"},
+	} {
+		codes := listing(tc.url, tc.key, "code")
+		assert.True(t, sort.StringsAreSorted(codes), tc.url)
+		assert.Equal(t, codes, listing(tc.url+"?sort=code", tc.key, "code"), tc.url)
+		assert.True(t, sort.StringsAreSorted(listing(tc.url+"?sort=name", tc.key, "name")), tc.url)
+	}
+
+	assert.Equal(t, []string{"AMLFU", "SILVA"}, listing("/regions/cities/IN/DN", "cities", "code"))
+
+	statusCode, _ := doRequest(t, ts.App, "GET", "/regions/countries?sort=size", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+}
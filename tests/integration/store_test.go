@@ -0,0 +1,58 @@
+package test
+
+import (
+	"challenge16/internal/data"
+	"challenge16/internal/regions"
+	"challenge16/internal/server"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStore is a fake store whose transactions fail to commit once failing is set.
+type failingStore struct {
+	data.Store
+	failing bool
+}
+
+func (s *failingStore) Update(fn func(tx data.Tx) error) error {
+	return s.Store.Update(func(tx data.Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if s.failing {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+}
+
+func TestDataBankOnFailingStore(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+
+	store := &failingStore{Store: data.NewMemoryStore()}
+	app := server.NewServer(1000000000, data.NewDataBank(store))
+
+	statusCode, _ := doRequest(t, app, "POST", "/permission/contract", "text/plain", `Permissions for DISTRIBUTOR1
+INCLUDE: IN`)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	store.failing = true
+
+	statusCode, response := doRequest(t, app, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR1","region":"KA-IN"}`)
+	assert.Equal(t, http.StatusInternalServerError, statusCode)
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", response.ResponseCode)
+
+	// errors decided inside the transaction are still reported as such
+	statusCode, response = doRequest(t, app, "POST", "/permission/disallow", "application/json", `{"distributor":"DISTRIBUTOR9","region":"KA-IN"}`)
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, DISTRIBUTOR_NOT_FOUND, response.ResponseCode)
+
+	// the failed change was rolled back
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	assert.Empty(t, permissions.Excluded)
+}
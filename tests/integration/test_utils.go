@@ -1,12 +1,19 @@
 package test
 
 import (
+	"challenge16/internal/data"
+	"challenge16/internal/dto"
 	"challenge16/internal/regions"
 	"challenge16/internal/server"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -44,7 +51,7 @@ func SetupIntegrationTest(t *testing.T) *TestSetup {
 		t.Fatalf("Error loading data into map: %v", err)
 	}
 
-	app := server.NewServer(1000000000) //effectively no rate limit
+	app := server.NewServer(1000000000, data.NewDataBank(data.NewMemoryStore())) //effectively no rate limit
 
 	ts.App = app
 	ts.Cleanup = func() {
@@ -62,3 +69,36 @@ func CleanupTest(t *testing.T, ts *TestSetup) {
 		ts.Cleanup()
 	}
 }
+
+// doRequest sends a request to the app, returning the status code and the decoded response.
+func doRequest(t *testing.T, app *fiber.App, method, url, contentType, body string) (int, Response) {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var response Response
+	require.NoError(t, json.Unmarshal(respBody, &response))
+	return resp.StatusCode, response
+}
+
+// decodeData decodes the data of a response into a T, the zero T if there's none.
+func decodeData[T any](t *testing.T, data interface{}) T {
+	var decoded T
+	if data != nil {
+		dataBytes, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(dataBytes, &decoded))
+	}
+	return decoded
+}
+
+func getPermissions(t *testing.T, app *fiber.App, distributor string) (int, dto.GetPermissionsData) {
+	statusCode, response := doRequest(t, app, "GET", "/permission/"+distributor+"?type=json", "", "")
+	return statusCode, decodeData[dto.GetPermissionsData](t, response.Data)
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeBoundContracts(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	app, databank := openPersistedApp(t, t.TempDir(), 0)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+VALID UNTIL: 2099-01-01
+INCLUDE: US`))
+	// the parent holds US until the contract expires, so the sub-distributor can be given it
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR3
+VALID FROM: 2099-01-01T00:00:00Z
+INCLUDE: FR`))
+
+	checks := []struct {
+		url    string
+		status string
+	}{
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US", "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR2&region=US", "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR3&region=FR", "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=US&at=2099-06-01T00:00:00Z", "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR1&region=IN&at=2099-06-01T00:00:00Z", "FULLY_ALLOWED"},
+		{"/permission/check?distributor=DISTRIBUTOR2&region=US&at=2099-06-01T00:00:00Z", "FULLY_DENIED"},
+		{"/permission/check?distributor=DISTRIBUTOR3&region=FR&at=2099-06-01T00:00:00Z", "FULLY_ALLOWED"},
+	}
+	for _, check := range checks {
+		statusCode, response := doRequest(t, app, "GET", check.url, "", "")
+		assert.Equal(t, http.StatusOK, statusCode, check.url)
+		assert.Equal(t, check.status, response.ResponseCode, check.url)
+	}
+
+	statusCode, _ := doRequest(t, app, "GET", "/permission/check?distributor=DISTRIBUTOR1&region=US&at=tomorrow", "", "")
+	assert.Equal(t, http.StatusBadRequest, statusCode)
+
+	// expiring the parent's contract cascades to the sub-distributor
+	changed, err := databank.ExpireContracts(time.Date(2099, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"DISTRIBUTOR1", "DISTRIBUTOR2", "DISTRIBUTOR3"}, changed)
+
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	_, permissions = getPermissions(t, app, "DISTRIBUTOR2")
+	assert.Empty(t, permissions.Included)
+	_, permissions = getPermissions(t, app, "DISTRIBUTOR3")
+	assert.ElementsMatch(t, []string{"FR"}, permissions.Included)
+}
+
+func TestInvalidContractValidity(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	contracts := []string{
+		// already expired
+		`Permissions for DISTRIBUTOR1
+VALID UNTIL: 2000-01-01
+INCLUDE: IN`,
+		// ends before it starts
+		`Permissions for DISTRIBUTOR1
+VALID FROM: 2099-01-01
+VALID UNTIL: 2098-01-01
+INCLUDE: IN`,
+		// not a date
+		`Permissions for DISTRIBUTOR1
+VALID FROM: next week
+INCLUDE: IN`,
+		// twice the same bound
+		`Permissions for DISTRIBUTOR1
+VALID UNTIL: 2099-01-01
+VALID UNTIL: 2098-01-01
+INCLUDE: IN`,
+	}
+	for _, contract := range contracts {
+		statusCode, _ := doRequest(t, ts.App, "POST", "/permission/contract", "text/plain", contract)
+		assert.Equal(t, http.StatusBadRequest, statusCode, contract)
+	}
+}
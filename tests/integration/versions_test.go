@@ -0,0 +1,136 @@
+package test
+
+import (
+	"challenge16/internal/regions"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func applyContractAs(t *testing.T, app *fiber.App, appliedBy, contract string) int {
+	req := httptest.NewRequest("POST", "/permission/contract", strings.NewReader(contract))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Applied-By", appliedBy)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestContractVersions(t *testing.T) {
+	require.NoError(t, regions.LoadDataIntoMap(csvFile))
+	dir := t.TempDir()
+	app, databank := openPersistedApp(t, dir, 0)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "bob", `Permissions for DISTRIBUTOR1
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, app, "bob", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US`))
+
+	statusCode, response := doRequest(t, app, "GET", "/contract/DISTRIBUTOR1/versions", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	versions := response.Data.(map[string]interface{})["versions"].([]interface{})
+	require.Len(t, versions, 2)
+	assert.Equal(t, "alice", versions[0].(map[string]interface{})["applied_by"])
+	assert.Equal(t, []interface{}{"US"}, versions[1].(map[string]interface{})["rules"].(map[string]interface{})["included"])
+
+	statusCode, response = doRequest(t, app, "GET", "/contract/DISTRIBUTOR1/versions/1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, "Permissions for DISTRIBUTOR1\nINCLUDE: IN", response.Data.(map[string]interface{})["text"])
+
+	// rolling back replaces the permissions, cascading to the sub-distributors
+	statusCode, response = doRequest(t, app, "POST", "/contract/DISTRIBUTOR1/rollback/1", "", "")
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, float64(3), response.Data.(map[string]interface{})["version"])
+	assert.Equal(t, []interface{}{"DISTRIBUTOR2"}, response.Data.(map[string]interface{})["affected_descendants"])
+
+	_, permissions := getPermissions(t, app, "DISTRIBUTOR1")
+	assert.ElementsMatch(t, []string{"IN"}, permissions.Included)
+	_, permissions = getPermissions(t, app, "DISTRIBUTOR2")
+	assert.Empty(t, permissions.Included)
+
+	// the parent checks run again: DISTRIBUTOR1 no longer holds US
+	statusCode, response = doRequest(t, app, "POST", "/contract/DISTRIBUTOR2/rollback/1", "", "")
+	assert.Equal(t, http.StatusConflict, statusCode)
+	assert.Equal(t, "EXCEEDS_PARENT_PERMISSIONS", response.ResponseCode)
+
+	statusCode, response = doRequest(t, app, "POST", "/contract/DISTRIBUTOR1/rollback/9", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+	assert.Equal(t, "VERSION_NOT_FOUND", response.ResponseCode)
+
+	statusCode, _ = doRequest(t, app, "GET", "/contract/DISTRIBUTOR9/versions", "", "")
+	assert.Equal(t, http.StatusNotFound, statusCode)
+
+	// the history is persisted along with the permissions
+	require.NoError(t, databank.Close())
+	app, databank = openPersistedApp(t, dir, 0)
+	defer databank.Close()
+
+	_, response = doRequest(t, app, "GET", "/contract/DISTRIBUTOR1/versions/3", "", "")
+	assert.Equal(t, float64(1), response.Data.(map[string]interface{})["rollback_of"])
+	assert.Equal(t, "Permissions for DISTRIBUTOR1\nINCLUDE: IN", response.Data.(map[string]interface{})["text"])
+}
+
+func TestRollbackRestoresEveryContractUpToTheVersion(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	for _, country := range []string{"US", "FR", "DE"} {
+		require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", "Permissions for DISTRIBUTOR1\nINCLUDE: "+country))
+	}
+
+	// contracts merge, so version 2 is what versions 1 and 2 gave
+	statusCode, response := doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR1/rollback/2", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, float64(4), response.Data.(map[string]interface{})["version"])
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"FR", "US"}, permissions.Included)
+
+	// a rollback stands for the versions it restored
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", "Permissions for DISTRIBUTOR1\nINCLUDE: IN"))
+	statusCode, _ = doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR1/rollback/5", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"FR", "IN", "US"}, permissions.Included)
+
+	statusCode, _ = doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR1/rollback/4", "", "")
+	require.Equal(t, http.StatusOK, statusCode)
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR1")
+	assert.Equal(t, []string{"FR", "US"}, permissions.Included)
+}
+
+func TestRollbackRestoresTheParent(t *testing.T) {
+	ts := SetupIntegrationTest(t)
+	defer CleanupTest(t, ts)
+
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR1
+INCLUDE: IN
+INCLUDE: US`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2
+INCLUDE: TN-IN`))
+	require.Equal(t, http.StatusOK, applyContractAs(t, ts.App, "alice", `Permissions for DISTRIBUTOR2 < DISTRIBUTOR1
+INCLUDE: US`))
+
+	// version 1 was applied before DISTRIBUTOR2 was placed under DISTRIBUTOR1
+	statusCode, response := doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR2/rollback/1", "", "")
+	require.Equal(t, http.StatusOK, statusCode, response.Error)
+	assert.Equal(t, float64(3), response.Data.(map[string]interface{})["version"])
+	_, response = doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR2/lineage", "", "")
+	assert.Equal(t, []interface{}{"DISTRIBUTOR2"}, response.Data.(map[string]interface{})["lineage"])
+	_, permissions := getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"TN-IN"}, permissions.Included)
+
+	// and rolling forward places it under DISTRIBUTOR1 again
+	statusCode, response = doRequest(t, ts.App, "POST", "/contract/DISTRIBUTOR2/rollback/2", "", "")
+	require.Equal(t, http.StatusOK, statusCode, response.Error)
+	_, response = doRequest(t, ts.App, "GET", "/distributor/DISTRIBUTOR2/lineage", "", "")
+	assert.Equal(t, []interface{}{"DISTRIBUTOR2", "DISTRIBUTOR1"}, response.Data.(map[string]interface{})["lineage"])
+	_, permissions = getPermissions(t, ts.App, "DISTRIBUTOR2")
+	assert.Equal(t, []string{"US", "TN-IN"}, permissions.Included)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/csv"
+	"fmt"
 	"os"
 )
 
@@ -27,6 +28,10 @@ func ParseCSV(filename string) ([]Data, error) {
 		return nil, err
 	}
 
+	if len(records) > 0 && len(records[0]) < 6 {
+		return nil, fmt.Errorf("expected 6 columns (city, province and country codes and names), found %d", len(records[0]))
+	}
+
 	var dataList []Data
 	for i, record := range records {
 		if i == 0 {